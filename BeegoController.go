@@ -0,0 +1,364 @@
+package commonlib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/astaxie/beego"
+	beecontext "github.com/astaxie/beego/context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime/debug"
+	"strings"
+)
+
+/**
+ * 控制器基类，统一输出响应格式
+ * 响应的http状态码根据响应码确定(见HttpStatusOf)，提示信息按请求头Accept-Language翻译，
+ * 按请求头Accept输出json、xml、MessagePack或protobuf格式
+ *
+ * example:
+ *   beego.BConfig.RecoverFunc = commonlib.RecoverPanic
+ *
+ *   type ScheduleController struct {
+ *     commonlib.BaseController
+ *   }
+ *
+ *   func (c *ScheduleController) Get() {
+ *     var params ScheduleParams
+ *     if !c.BindParams(&params) {
+ *       return
+ *     }
+ *     list, err := findSchedules(params)
+ *     if err != nil {
+ *       c.ServeError(err)
+ *       return
+ *     }
+ *     c.ServeSuccess("", list)
+ *   }
+ */
+type BaseController struct {
+	beego.Controller
+}
+
+// 请求的context，包含RequestIdFilter设置的请求ID等日志字段
+func (c *BaseController) Context() context.Context {
+	return c.Ctx.Request.Context()
+}
+
+// 输出请求context中日志字段的日志
+func (c *BaseController) Log() *MyLogger {
+	return LogFrom(c.Context())
+}
+
+// 请求使用的语言
+func (c *BaseController) Locale() string {
+	return MatchLocale(c.Ctx.Input.Header("Accept-Language"))
+}
+
+// 输出响应，res为*Response或Build*Message返回的map，格式由请求头Accept确定(见NegotiateFormat)
+func (c *BaseController) ServeResponse(res interface{}) {
+	ServeResponse(c.Ctx, res)
+}
+
+func (c *BaseController) ServeSuccess(message string, content interface{}) {
+	c.ServeResponse(NewSuccessResponse(message, content))
+}
+
+func (c *BaseController) ServePage(message string, content interface{}, pager *Pager) {
+	c.ServeResponse(NewPageResponse(message, content, pager))
+}
+
+// 输出失败响应，响应码见NewErrorResponseFrom
+func (c *BaseController) ServeError(err error) {
+	c.ServeResponse(NewErrorResponseFrom(err))
+}
+
+// 输出失败响应，message为空时使用错误码的默认提示
+func (c *BaseController) ServeCodeError(code int, message string) {
+	c.ServeResponse(NewCodeErrorResponse(code, message))
+}
+
+// 请求中的分页参数，见ParsePageParams
+func (c *BaseController) PageParams() (int, int) {
+	return ParsePageParams(c.Ctx.Request.URL.Query(), 0)
+}
+
+/**
+ * 绑定请求参数到结构体，失败时输出参数错误响应
+ * @param obj	结构体指针
+ *
+ * return 是否成功，失败时已输出响应，调用方直接返回即可
+ */
+func (c *BaseController) BindParams(obj interface{}) bool {
+	if err := BindRequest(c.Ctx, obj); err != nil {
+		var tagErr *ValidateTagError
+		if errors.As(err, &tagErr) {
+			// 校验标签错误是代码错误，不返回给调用方
+			serveResponse(c.Ctx, http.StatusInternalServerError, NewCodeErrorResponse(CodeFail, ""))
+			return false
+		}
+		c.ServeResponse(paramsErrorResponse(err))
+		return false
+	}
+	return true
+}
+
+/**
+ * 输出响应，用于过滤器等没有控制器的场景
+ * @param res	*Response或Build*Message返回的map
+ */
+func ServeResponse(ctx *beecontext.Context, res interface{}) {
+	serveResponse(ctx, responseStatus(res), res)
+}
+
+func serveResponse(ctx *beecontext.Context, status int, res interface{}) {
+	locale := MatchLocale(ctx.Input.Header("Accept-Language"))
+	res = Localize(res, locale)
+
+	contentType, body, err := MarshalResponse(res, ctx.Input.Header("Accept"))
+	if err != nil {
+		Log.Error("序列化响应发生错误:", err)
+		contentType, status = ContentTypeJSON, http.StatusInternalServerError
+		body, _ = json.Marshal(NewCodeErrorResponse(CodeFail, "").Localize(locale))
+	}
+
+	if contentType == ContentTypeJSON || contentType == ContentTypeXML {
+		contentType += "; charset=utf-8"
+	}
+	ctx.Output.Header("Content-Type", contentType)
+	ctx.Output.SetStatus(status)
+	ctx.Output.Body(body)
+}
+
+// 响应对应的http状态码
+func responseStatus(res interface{}) int {
+	switch r := res.(type) {
+	case interface{ responseCode() int }:
+		return HttpStatusOf(r.responseCode())
+	case map[string]interface{}:
+		if code, ok := r["code"].(int); ok {
+			return HttpStatusOf(code)
+		}
+	}
+	return http.StatusOK
+}
+
+/**
+ * 处理请求中的panic，输出CodeFail响应及500状态码，panic信息只记录到日志
+ *
+ * example:
+ *   beego.BConfig.RecoverFunc = commonlib.RecoverPanic
+ */
+func RecoverPanic(ctx *beecontext.Context) {
+	err := recover()
+	if err == nil || err == beego.ErrAbort {
+		return
+	}
+
+	LogFrom(ctx.Request.Context()).Error("请求处理发生panic:", ctx.Request.Method, " ", ctx.Request.URL.String(), ",错误:", err, "\n", string(debug.Stack()))
+
+	if ctx.ResponseWriter != nil && ctx.ResponseWriter.Started {
+		return
+	}
+	serveResponse(ctx, http.StatusInternalServerError, NewCodeErrorResponse(CodeFail, ""))
+}
+
+/**
+ * 绑定请求参数到结构体
+ * 路由参数、url参数及表单参数按字段标签form匹配(没有时使用json标签，再没有时使用字段名)，
+ * 请求体为json时再按json解析请求体，绑定后按字段标签校验(见Validate)，
+ * 结构体实现了Validate() error时再调用该方法校验
+ *
+ * 整数超出字段类型的范围时为参数类型错误，time.Time支持 2006-01-02 15:04:05、2006-01-02 及RFC3339格式
+ *
+ * return 错误信息，参数类型错误时为FieldErrors
+ *
+ * example:
+ *   type ScheduleParams struct {
+ *     ChildId string `form:"childId"`
+ *     Date    time.Time `form:"date"`
+ *     Status  []int `form:"status"`
+ *   }
+ */
+func BindRequest(ctx *beecontext.Context, obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("参数绑定对象必须为结构体指针")
+	}
+
+	if err := ctx.Request.ParseForm(); err != nil {
+		return err
+	}
+
+	values := make(url.Values, len(ctx.Request.Form))
+	for key, val := range ctx.Request.Form {
+		values[key] = val
+	}
+	for key, val := range ctx.Input.Params() {
+		if strings.HasPrefix(key, ":") {
+			values.Set(key[1:], val)
+		}
+	}
+
+	var errs FieldErrors
+	bindValues(values, rv.Elem(), &errs)
+
+	if strings.HasPrefix(ctx.Request.Header.Get("Content-Type"), "application/json") {
+		if err := bindJSON(ctx, obj); err != nil {
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				return err
+			}
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if err := Validate(obj); err != nil {
+		return err
+	}
+
+	if v, ok := obj.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
+
+func bindJSON(ctx *beecontext.Context, obj interface{}) error {
+	body := ctx.Input.RequestBody
+	if len(body) == 0 && ctx.Request.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(ctx.Request.Body); err != nil {
+			return err
+		}
+		ctx.Input.RequestBody = body
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, obj); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return NewFieldError(typeErr.Field, "valid.type", typeErr.Type.String())
+		}
+		return fmt.Errorf("请求体json格式错误: %v", err)
+	}
+
+	return nil
+}
+
+func bindValues(values url.Values, rv reflect.Value, errs *FieldErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			bindValues(values, fv, errs)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := paramName(field)
+		if name == "-" {
+			continue
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		if err := setParam(fv, vals); err != nil {
+			var keyErr *KeyError
+			if errors.As(err, &keyErr) {
+				*errs = append(*errs, NewFieldError(name, keyErr.Key, keyErr.Args...))
+			} else {
+				*errs = append(*errs, NewFieldError(name, "valid.format", err.Error()))
+			}
+		}
+	}
+}
+
+// 字段对应的参数名
+func paramName(field reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func setParam(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), 0, len(vals))
+		for _, val := range vals {
+			v, err := convertParam(val, fv.Type().Elem())
+			if err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, v)
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	// 非字符串类型的空参数视为未传
+	if vals[0] == "" && fv.Kind() != reflect.String {
+		return nil
+	}
+
+	v, err := convertParam(vals[0], fv.Type())
+	if err != nil {
+		return err
+	}
+	fv.Set(v)
+
+	return nil
+}
+
+// 转换参数值，无法转换时返回valid.type的KeyError
+func convertParam(value string, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		v, err := convertParam(value, t.Elem())
+		if err != nil {
+			return v, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	}
+
+	typeName := t.Kind().String()
+	if t.Kind() == reflect.Struct {
+		typeName = t.Name()
+	}
+
+	// 错误信息中不包含参数值
+	v, err := TypeConversion(value, typeName)
+	if err != nil || !v.Type().ConvertibleTo(t) {
+		return reflect.Value{}, NewKeyError("valid.type", t.String())
+	}
+
+	return v.Convert(t), nil
+}
+
+// 绑定参数的错误转换为参数错误响应
+func paramsErrorResponse(err error) *Message {
+	res := NewErrorResponseFrom(err)
+	if res.Code == CodeFail {
+		return NewCodeErrorResponse(CodeParamsError, err.Error())
+	}
+	return res
+}
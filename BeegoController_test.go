@@ -0,0 +1,129 @@
+package commonlib
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bindTestParams struct {
+	Name    string            `form:"name"`
+	Age     int               `form:"age"`
+	Date    time.Time         `form:"date"`
+	Status  []int             `form:"status"`
+	Page    *int              `form:"page"`
+	Extra   map[string]string `form:"extra"`
+	Nested  struct{ A int }   `form:"nested"`
+	Enabled bool              `form:"enabled"`
+}
+
+func TestBindValues(t *testing.T) {
+	cases := []struct {
+		name   string
+		values url.Values
+		field  string // 期望出错的参数，为空时期望绑定成功
+	}{
+		{"正常", url.Values{"name": {"a"}, "age": {"18"}, "date": {"2024-01-02 03:04:05"}, "status": {"1", "2"}, "page": {"3"}, "enabled": {"true"}}, ""},
+		{"数字格式错误", url.Values{"age": {"abc"}}, "age"},
+		{"切片元素格式错误", url.Values{"status": {"1", "x"}}, "status"},
+		{"指针格式错误", url.Values{"page": {"x"}}, "page"},
+		{"不支持的map字段", url.Values{"extra": {"a"}}, "extra"},
+		{"不支持的结构体字段", url.Values{"nested": {"a"}}, "nested"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var params bindTestParams
+			var errs FieldErrors
+			bindValues(c.values, reflect.ValueOf(&params).Elem(), &errs)
+
+			if c.field == "" {
+				if len(errs) > 0 {
+					t.Fatalf("bindValues() errs = %v", errs)
+				}
+				if params.Name != "a" || params.Age != 18 || len(params.Status) != 2 || params.Page == nil || *params.Page != 3 || !params.Enabled {
+					t.Errorf("bindValues() params = %+v", params)
+				}
+				return
+			}
+
+			if len(errs) != 1 || errs[0].Field != c.field || errs[0].Key != "valid.type" {
+				t.Fatalf("bindValues() errs = %v, expected valid.type on %s", errs, c.field)
+			}
+			// 错误信息中不包含用户输入的值
+			for _, val := range c.values[c.field] {
+				if val != "1" && errs[0].Message == val {
+					t.Errorf("错误信息不应该是参数值: %s", errs[0].Message)
+				}
+			}
+		})
+	}
+}
+
+type bindKindParams struct {
+	Int8    int8      `form:"int8"`
+	Int16   int16     `form:"int16"`
+	Int32   int32     `form:"int32"`
+	Uint    uint      `form:"uint"`
+	Uint8   uint8     `form:"uint8"`
+	Uint16  uint16    `form:"uint16"`
+	Uint32  uint32    `form:"uint32"`
+	Uint64  uint64    `form:"uint64"`
+	Float32 float32   `form:"float32"`
+	Date    time.Time `form:"date"`
+}
+
+func TestBindValuesKinds(t *testing.T) {
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name     string
+		values   url.Values
+		expected bindKindParams
+		field    string // 期望出错的参数，为空时期望绑定成功
+	}{
+		{"int8", url.Values{"int8": {"-128"}}, bindKindParams{Int8: -128}, ""},
+		{"int8溢出", url.Values{"int8": {"300"}}, bindKindParams{}, "int8"},
+		{"int16", url.Values{"int16": {"30000"}}, bindKindParams{Int16: 30000}, ""},
+		{"int16溢出", url.Values{"int16": {"40000"}}, bindKindParams{}, "int16"},
+		{"int32溢出", url.Values{"int32": {"3000000000"}}, bindKindParams{}, "int32"},
+		{"uint", url.Values{"uint": {"7"}}, bindKindParams{Uint: 7}, ""},
+		{"uint负数", url.Values{"uint": {"-1"}}, bindKindParams{}, "uint"},
+		{"uint8", url.Values{"uint8": {"255"}}, bindKindParams{Uint8: 255}, ""},
+		{"uint8溢出", url.Values{"uint8": {"256"}}, bindKindParams{}, "uint8"},
+		{"uint16", url.Values{"uint16": {"65535"}}, bindKindParams{Uint16: 65535}, ""},
+		{"uint32", url.Values{"uint32": {"4000000000"}}, bindKindParams{Uint32: 4000000000}, ""},
+		{"uint64", url.Values{"uint64": {"18446744073709551615"}}, bindKindParams{Uint64: 18446744073709551615}, ""},
+		{"float32溢出", url.Values{"float32": {"1e40"}}, bindKindParams{}, "float32"},
+		{"日期", url.Values{"date": {"2024-01-02"}}, bindKindParams{Date: date}, ""},
+		{"RFC3339", url.Values{"date": {"2024-01-02T00:00:00+08:00"}}, bindKindParams{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.FixedZone("", 8*3600))}, ""},
+		{"日期格式错误", url.Values{"date": {"2024/01/02"}}, bindKindParams{}, "date"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var params bindKindParams
+			var errs FieldErrors
+			bindValues(c.values, reflect.ValueOf(&params).Elem(), &errs)
+
+			if c.field == "" {
+				if len(errs) > 0 {
+					t.Fatalf("bindValues() errs = %v", errs)
+				}
+				if !params.Date.Equal(c.expected.Date) {
+					t.Errorf("bindValues() date = %v, expected %v", params.Date, c.expected.Date)
+				}
+				params.Date, c.expected.Date = time.Time{}, time.Time{}
+				if params != c.expected {
+					t.Errorf("bindValues() params = %+v, expected %+v", params, c.expected)
+				}
+				return
+			}
+
+			if len(errs) != 1 || errs[0].Field != c.field || errs[0].Key != "valid.type" {
+				t.Errorf("bindValues() errs = %v, expected valid.type on %s", errs, c.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package commonlib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// *sql.DB 或 *sql.Tx
+type dbPreparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+func preparerOf(opObj interface{}) (dbPreparer, bool) {
+	switch obj := opObj.(type) {
+	case *sql.DB:
+		return obj, true
+	case *sql.Tx:
+		return obj, true
+	}
+	return nil, false
+}
+
+/****
+ * 数据查询，ctx用于取消查询，发生错误时日志中包含ctx中的字段(见WithLogFields)
+ * @param ctx		请求的context
+ * @param opObj		操作数据库对象 *sql.DB | *sql.Tx
+ * @param sqlStr	操作的sql语句
+ * @param args		参数列表
+ *
+ * return 数据集， 错误信息
+ *
+ * example:
+ *   res, err := QueryContext(ctx, db, "select fields from table_name where field_name=?;", "hello")
+ */
+func QueryContext(ctx context.Context, opObj interface{}, sqlStr string, args ...interface{}) ([]map[string]string, error) {
+	log := LogFrom(ctx)
+
+	preparer, ok := preparerOf(opObj)
+	if !ok {
+		return nil, errors.New("查询错误: 无法获取数据库操作对象")
+	}
+
+	stmt, err := preparer.PrepareContext(ctx, sqlStr)
+	if err != nil {
+		log.Error(err, StringField("sql", sqlStr))
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		log.Error(err, StringField("sql", sqlStr))
+		return nil, err
+	}
+
+	result, err := rowsToMapLog(rows, log)
+	if err != nil {
+		log.Error(err, StringField("sql", sqlStr))
+		return nil, err
+	}
+
+	return result, err
+}
+
+// 数据查询(一个)，没有数据时返回空map，见QueryContext
+func QueryOneContext(ctx context.Context, opObj interface{}, sqlStr string, args ...interface{}) (map[string]string, error) {
+	result, err := QueryContext(ctx, opObj, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) > 0 {
+		return result[0], nil
+	}
+
+	return make(map[string]string), nil
+}
+
+/****
+ * 数据增删改，ctx用于取消操作，发生错误时日志中包含ctx中的字段
+ * @param ctx		请求的context
+ * @param opObj		操作数据库对象 *sql.DB | *sql.Tx
+ * @param sqlStr	操作的sql语句
+ * @param args		参数列表
+ *
+ * return 处理结果， 错误信息
+ *
+ * example:
+ *   res, err := ExecContext(ctx, tx, "update table_name set field=? where id=?;", "hello", 1)
+ */
+func ExecContext(ctx context.Context, opObj interface{}, sqlStr string, args ...interface{}) (sql.Result, error) {
+	log := LogFrom(ctx)
+
+	preparer, ok := preparerOf(opObj)
+	if !ok {
+		return nil, errors.New("数据处理异常: 无法获取数据库操作对象")
+	}
+
+	stmt, err := preparer.PrepareContext(ctx, sqlStr)
+	if err != nil {
+		log.Error(err, StringField("sql", sqlStr))
+		return nil, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		log.Error(err, StringField("sql", sqlStr))
+		return nil, err
+	}
+
+	return result, err
+}
+
+/**
+ * 包含事务的数据库处理，见DbTransactionAction
+ * @param ctx		请求的context，用于取消事务及日志字段
+ * @param txAction	数据库操作的具体方法
+ *
+ * return 处理结果， 错误信息
+ *
+ * example:
+ *   res, err := DbTransactionActionContext(ctx, func(tx *sql.Tx) (map[string]interface{}, error) {
+ *     _, err := ExecContext(ctx, tx, inSql, inParams...)
+ *     return nil, err
+ *   })
+ */
+func DbTransactionActionContext(ctx context.Context, txAction func(*sql.Tx) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	log := LogFrom(ctx)
+
+	db := GetMySQL()
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("db.Begin: ", err.Error())
+		return NewKeyErrorResponse(CodeDbError, "db.begin", err.Error()).ToMap(), err
+	}
+
+	t := time.Now()
+	actionResult, err := txAction(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Error("tx.Rollback: ", rbErr.Error())
+		}
+		return actionResult, err
+	}
+	log.Debug("事务处理时间: ", time.Now().Sub(t))
+
+	if err = tx.Commit(); err != nil {
+		log.Error("tx.Commit: ", err.Error())
+		return NewKeyErrorResponse(CodeDbError, "db.commit", err.Error()).ToMap(), err
+	}
+	if err = db.Close(); err != nil {
+		log.Error("db.Close: ", err.Error())
+		return NewKeyErrorResponse(CodeDbError, "db.close", err.Error()).ToMap(), err
+	}
+
+	return actionResult, nil
+}
+
+/****
+ * 分页查询，见DbPage
+ * @param ctx			请求的context
+ * @param opObj			操作数据库对象 *sql.DB | *sql.Tx
+ * @param countSql		count语句，结果列名为count(1)
+ * @param dataSql		数据语句，末尾会追加limit
+ * @param countParams	count参数
+ * @param params		数据参数
+ * @param pageId		第几页
+ * @param recPerPage	每页几条
+ *
+ * return 数据集，pager对象， 错误信息
+ *
+ * example:
+ *   list, pager, err := PageContext(ctx, db, countSql, dataSql, countParams, params, pageId, recPerPage)
+ */
+func PageContext(ctx context.Context, opObj interface{}, countSql string, dataSql string, countParams []interface{}, params []interface{}, pageId, recPerPage int) ([]map[string]string, *Pager, error) {
+	rec, err := QueryOneContext(ctx, opObj, countSql, countParams...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	total, _ := strconv.Atoi(rec["count(1)"])
+
+	pager := NewPager(pageId, recPerPage, total)
+
+	dataSql += " limit ?,?"
+	params = append(params, pager.Offset, pager.RecPerPage)
+
+	dataRec, err := QueryContext(ctx, opObj, dataSql, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dataRec, pager, nil
+}
@@ -0,0 +1,168 @@
+package commonlib
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// 测试用的数据库驱动，查询返回固定的两行数据，sql为bad时返回错误
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct {
+	query string
+}
+
+type fakeRows struct {
+	index int
+}
+
+func init() {
+	sql.Register("commonlibfake", fakeDriver{})
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if query == "bad" {
+		return nil, errors.New("syntax error")
+	}
+	return &fakeStmt{query: query}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeConn{}, nil
+}
+
+func (fakeConn) Commit() error {
+	return nil
+}
+
+func (fakeConn) Rollback() error {
+	return nil
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "name"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= 2 {
+		return io.EOF
+	}
+	r.index++
+	dest[0] = int64(r.index)
+	dest[1] = []byte(fmt.Sprint("name", r.index))
+	return nil
+}
+
+func TestLegacyDbDelegates(t *testing.T) {
+	db, err := sql.Open("commonlibfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	expected := `[map[id:1 name:name1] map[id:2 name:name2]]`
+
+	legacy, err := DbQuery(db, "select id, name from child")
+	withCtx, ctxErr := QueryContext(ctx, db, "select id, name from child")
+	if err != nil || ctxErr != nil || fmt.Sprint(legacy) != expected || fmt.Sprint(withCtx) != expected {
+		t.Errorf("DbQuery() = %v, %v, QueryContext() = %v, %v", legacy, err, withCtx, ctxErr)
+	}
+
+	one, err := DbQueryOne(db, "select id, name from child")
+	if err != nil || one["name"] != "name1" {
+		t.Errorf("DbQueryOne() = %v, %v", one, err)
+	}
+
+	res, err := DbUpdate(db, "update child set name=? where id=?", "a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 2 {
+		t.Errorf("RowsAffected() = %d", affected)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := TxQuery(tx, "select id, name from child")
+	tx.Rollback()
+	if err != nil || fmt.Sprint(rows) != expected {
+		t.Errorf("TxQuery() = %v, %v", rows, err)
+	}
+
+	if _, err = DbQuery(db, "bad"); err == nil {
+		t.Error("sql错误时应该返回错误")
+	}
+	if _, err = QueryContext(ctx, "db", "select 1"); err == nil {
+		t.Error("不是*sql.DB或*sql.Tx时应该返回错误")
+	}
+}
+
+func TestDbContextLogFields(t *testing.T) {
+	defer SetLogger(nil)
+
+	buf := &bytes.Buffer{}
+	SetLogger(NewWriterLogger(buf, LogFormatJSON))
+
+	db, err := sql.Open("commonlibfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(WithRequestId(context.Background(), "req-1"))
+	cancel()
+
+	if _, err = QueryContext(ctx, db, "select 1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("QueryContext() error = %v", err)
+	}
+	if _, err = ExecContext(WithRequestId(context.Background(), "req-2"), db, "bad"); err == nil {
+		t.Error("sql错误时应该返回错误")
+	}
+
+	output := buf.String()
+	for _, text := range []string{`"requestId":"req-1"`, `"requestId":"req-2"`, `"sql":"bad"`} {
+		if !strings.Contains(output, text) {
+			t.Errorf("日志中没有%s: %s", text, output)
+		}
+	}
+}
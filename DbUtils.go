@@ -1,10 +1,9 @@
 package commonlib
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"strconv"
-	"time"
 )
 
 /**
@@ -65,47 +64,7 @@ func DbAction(dbAction func(*sql.DB) (map[string]interface{}, error)) (map[strin
  * })
  */
 func DbTransactionAction(txAction func(*sql.Tx) (map[string]interface{}, error)) (map[string]interface{}, error) {
-	db := GetMySQL()
-	defer db.Close()
-
-	// 开启事务
-	tx, err := db.Begin()
-	if err != nil {
-		Log.Error("db.Begin: ", err.Error())
-		return BuildDbErrorMessage("开启事务时，数据库异常： " + err.Error()), err
-	}
-	defer func() {
-		if err != nil && tx != nil {
-			// 事务回滚
-			if rbErr := tx.Rollback(); rbErr != nil {
-				Log.Error("tx.Rollback: ", rbErr.Error())
-				return
-			}
-		}
-	}()
-	t := time.Now()
-	/**
-	 * @param db 用于事务内查询操作，无法查询到当前事务影响的结果
-	 * @param tx 当前操作的事务
-	 */
-	actionResult, err := txAction(tx)
-	if err != nil {
-		return actionResult, err
-	}
-	Log.Debug("事务处理时间: ", time.Now().Sub(t))
-
-	// 提交事务
-	if err = tx.Commit(); err != nil {
-		Log.Error("tx.Commit: ", err.Error())
-		return BuildDbErrorMessage("提交事务，数据库异常" + err.Error()), err
-	}
-	// 关闭数据库连接
-	if err = db.Close(); err != nil {
-		Log.Error("db.Close: ", err.Error())
-		return BuildDbErrorMessage("关闭数据库连接，数据库异常" + err.Error()), err
-	}
-
-	return actionResult, err
+	return DbTransactionActionContext(context.Background(), txAction)
 }
 
 /****
@@ -121,17 +80,11 @@ func DbTransactionAction(txAction func(*sql.Tx) (map[string]interface{}, error))
  *   res, err := Insert(db, "insert into table_name ('filed') values (?);", "hello")
  */
 func Insert(opObj interface{}, sqlStr string, args ...interface{}) (sql.Result, error) {
-	db, ok := opObj.(*sql.DB)
-	if ok {
-		return DbInsert(db, sqlStr, args...)
+	if _, ok := preparerOf(opObj); !ok {
+		return nil, errors.New("插入失败: 无法获取数据库操作对象")
 	}
 
-	tx, ok := opObj.(*sql.Tx)
-	if ok {
-		return TxInsert(tx, sqlStr, args...)
-	}
-
-	return nil, errors.New("插入失败: 无法获取数据库操作对象")
+	return ExecContext(context.Background(), opObj, sqlStr, args...)
 }
 
 /****
@@ -147,17 +100,11 @@ func Insert(opObj interface{}, sqlStr string, args ...interface{}) (sql.Result,
  *   res, err := Delete(db, "delete from table_name where id=?;", 1)
  */
 func Delete(opObj interface{}, sqlStr string, args ...interface{}) (sql.Result, error) {
-	db, ok := opObj.(*sql.DB)
-	if ok {
-		return DbDelete(db, sqlStr, args...)
-	}
-
-	tx, ok := opObj.(*sql.Tx)
-	if ok {
-		return TxDelete(tx, sqlStr, args...)
+	if _, ok := preparerOf(opObj); !ok {
+		return nil, errors.New("删除失败: 无法获取数据库操作对象")
 	}
 
-	return nil, errors.New("删除失败: 无法获取数据库操作对象")
+	return ExecContext(context.Background(), opObj, sqlStr, args...)
 }
 
 /****
@@ -173,17 +120,11 @@ func Delete(opObj interface{}, sqlStr string, args ...interface{}) (sql.Result,
  *   res, err := Update(db, "update table_name set field=?;", "hello")
  */
 func Update(opObj interface{}, sqlStr string, args ...interface{}) (sql.Result, error) {
-	db, ok := opObj.(*sql.DB)
-	if ok {
-		return DbUpdate(db, sqlStr, args...)
+	if _, ok := preparerOf(opObj); !ok {
+		return nil, errors.New("更新失败: 无法获取数据库操作对象")
 	}
 
-	tx, ok := opObj.(*sql.Tx)
-	if ok {
-		return TxUpdate(tx, sqlStr, args...)
-	}
-
-	return nil, errors.New("更新失败: 无法获取数据库操作对象")
+	return ExecContext(context.Background(), opObj, sqlStr, args...)
 }
 
 /****
@@ -199,17 +140,7 @@ func Update(opObj interface{}, sqlStr string, args ...interface{}) (sql.Result,
  *   res, err := Query(db, "select fields from table_name where field_name=?;", "hello")
  */
 func Query(opObj interface{}, sqlStr string, args ...interface{}) ([]map[string]string, error) {
-	db, ok := opObj.(*sql.DB)
-	if ok {
-		return DbQuery(db, sqlStr, args...)
-	}
-
-	tx, ok := opObj.(*sql.Tx)
-	if ok {
-		return TxQuery(tx, sqlStr, args...)
-	}
-
-	return nil, errors.New("查询错误: 无法获取数据库操作对象")
+	return QueryContext(context.Background(), opObj, sqlStr, args...)
 }
 
 /****
@@ -225,17 +156,7 @@ func Query(opObj interface{}, sqlStr string, args ...interface{}) ([]map[string]
  *   res, err := QueryOne(db, "select fields from table_name where field_name=?;", "hello")
  */
 func QueryOne(opObj interface{}, sqlStr string, args ...interface{}) (map[string]string, error) {
-	db, ok := opObj.(*sql.DB)
-	if ok {
-		return DbQueryOne(db, sqlStr, args...)
-	}
-
-	tx, ok := opObj.(*sql.Tx)
-	if ok {
-		return TxQueryOne(tx, sqlStr, args...)
-	}
-
-	return nil, errors.New("查询错误: 无法获取数据库操作对象")
+	return QueryOneContext(context.Background(), opObj, sqlStr, args...)
 }
 
 /****
@@ -251,7 +172,7 @@ func QueryOne(opObj interface{}, sqlStr string, args ...interface{}) (map[string
  *   res, err := DbInsert(db, "insert into table_name ('filed') values (?);", "hello")
  */
 func DbInsert(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error) {
-	return dbOperation(db, sqlStr, args...)
+	return ExecContext(context.Background(), db, sqlStr, args...)
 }
 
 /****
@@ -267,7 +188,7 @@ func DbInsert(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error
  *   res, err := DbDelete(db, "delete from table_name where id=?;", 1)
  */
 func DbDelete(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error) {
-	return dbOperation(db, sqlStr, args...)
+	return ExecContext(context.Background(), db, sqlStr, args...)
 }
 
 /****
@@ -283,7 +204,7 @@ func DbDelete(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error
  *   res, err := DbUpdate(db, "update table_name set field=?;", "hello")
  */
 func DbUpdate(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error) {
-	return dbOperation(db, sqlStr, args...)
+	return ExecContext(context.Background(), db, sqlStr, args...)
 }
 
 /****
@@ -299,29 +220,7 @@ func DbUpdate(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error
  *   res, err := DbQuery(db, "select fields from table_name where field_name=?;", "hello")
  */
 func DbQuery(db *sql.DB, sqlStr string, args ...interface{}) ([]map[string]string, error) {
-	stmt, err := db.Prepare(sqlStr)
-	defer stmt.Close()
-
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	rows, err := stmt.Query(args...)
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	result, err := rowsToMap(rows)
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	//	Log.Debug(sqlStr, args)
-
-	return result, err
+	return QueryContext(context.Background(), db, sqlStr, args...)
 }
 
 /****
@@ -337,19 +236,7 @@ func DbQuery(db *sql.DB, sqlStr string, args ...interface{}) ([]map[string]strin
  *   res, err := DbQueryOne(db, "select fields from table_name where field_name=?;", "hello")
  */
 func DbQueryOne(db *sql.DB, sqlStr string, args ...interface{}) (map[string]string, error) {
-	result, err := DbQuery(db, sqlStr, args...)
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	//	Log.Debug(sqlStr, args)
-
-	if len(result) > 0 {
-		return result[0], nil
-	}
-
-	return make(map[string]string), err
+	return QueryOneContext(context.Background(), db, sqlStr, args...)
 }
 
 /**
@@ -364,26 +251,7 @@ func DbQueryOne(db *sql.DB, sqlStr string, args ...interface{}) (map[string]stri
  *   res, err := TxQuery(tx, "select fields from table_name where field_name=?;", "hello")
  */
 func TxQuery(tx *sql.Tx, sqlStr string, args ...interface{}) ([]map[string]string, error) {
-	stmt, err := tx.Prepare(sqlStr)
-
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	rows, err := stmt.Query(args...)
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	result, err := rowsToMap(rows)
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	return result, err
+	return QueryContext(context.Background(), tx, sqlStr, args...)
 }
 
 /****
@@ -398,19 +266,7 @@ func TxQuery(tx *sql.Tx, sqlStr string, args ...interface{}) ([]map[string]strin
  *   res, err := TxQueryOne(tx, "select fields from table_name where field_name=?;", "hello")
  */
 func TxQueryOne(tx *sql.Tx, sqlStr string, args ...interface{}) (map[string]string, error) {
-	result, err := TxQuery(tx, sqlStr, args...)
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	//	Log.Debug(sqlStr, args)
-
-	if len(result) > 0 {
-		return result[0], nil
-	}
-
-	return make(map[string]string), err
+	return QueryOneContext(context.Background(), tx, sqlStr, args...)
 }
 
 /**
@@ -425,7 +281,7 @@ func TxQueryOne(tx *sql.Tx, sqlStr string, args ...interface{}) (map[string]stri
  *   res, err := TxInsert(tx, "insert into table_name ('filed') values (?);", "hello")
  */
 func TxInsert(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, error) {
-	return txOperation(tx, sqlStr, args...)
+	return ExecContext(context.Background(), tx, sqlStr, args...)
 }
 
 /**
@@ -440,7 +296,7 @@ func TxInsert(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, error
  *   res, err := TxDelete(tx, "delete from table_name where id=?;", 1)
  */
 func TxDelete(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, error) {
-	return txOperation(tx, sqlStr, args...)
+	return ExecContext(context.Background(), tx, sqlStr, args...)
 }
 
 /**
@@ -455,66 +311,7 @@ func TxDelete(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, error
  *   res, err := TxUpdate(tx, "update table_name set field=?;", "hello")
  */
 func TxUpdate(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, error) {
-	return txOperation(tx, sqlStr, args...)
-}
-
-/****
- * 数据增删改处理
- * @param db     操作数据库对象
- * @param sqlStr 操作的sql语句
- * @param args   参数列表
- *
- * return 处理结果， 错误信息
- */
-func dbOperation(db *sql.DB, sqlStr string, args ...interface{}) (sql.Result, error) {
-	stmt, err := db.Prepare(sqlStr)
-
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	result, err := stmt.Exec(args...)
-
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	return result, err
-}
-
-/**
- * 数据增删改处理(事务)
- * @param tx     当前处理的事务
- * @param sqlStr 待处理的sql
- * @param args   sql所需的参数列表
- *
- * return 处理结果， 错误信息
- */
-func txOperation(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, error) {
-	stmt, err := tx.Prepare(sqlStr)
-	if err != nil {
-		Log.Error("tx.Prepare: ", err.Error())
-		return nil, err
-	}
-	defer func() {
-		if stmtErr := stmt.Close(); stmtErr != nil {
-			Log.Error("stmt.Close: ", stmtErr.Error())
-			return
-		}
-	}()
-
-	result, err := stmt.Exec(args...)
-
-	if err != nil {
-		Log.Error(err)
-		return nil, err
-	}
-
-	//Log.Debug(sqlStr)
-
-	return result, err
+	return ExecContext(context.Background(), tx, sqlStr, args...)
 }
 
 /****
@@ -531,36 +328,13 @@ func txOperation(tx *sql.Tx, sqlStr string, args ...interface{}) (sql.Result, er
  *
  * example:
  *   db := GetMySQL()
- *   res, err := DbQuery(db, "select fields from table_name where field_name=?;", "hello")
+ *   list, pager, err := DbPage(db, countSql, dataSql, countParams, params, pageId, recPerPage)
  */
 func DbPage(db *sql.DB, countSql string, dataSql string, countParams []interface{}, params []interface{}, pageId, recPerPage int) ([]map[string]string, *Pager, error) {
-
-	rec, err := DbQueryOne(db, countSql, countParams...)
-
-	if err != nil {
-		Log.Error(err)
-		return nil, nil, err
-	}
-
-	total, _ := strconv.Atoi(rec["count(1)"])
-
-	pager := buildPager(pageId, recPerPage, total)
-
-	dataSql += " limit ?,?"
-	params = append(params, (pager.PageId-1)*pager.RecPerPage)
-	params = append(params, pager.RecPerPage)
-
-	dataRec, err := DbQuery(db, dataSql, params...)
-
-	if err != nil {
-		Log.Error(err)
-		return nil, nil, err
-	}
-
-	return dataRec, buildPager(pageId, recPerPage, total), nil
+	return PageContext(context.Background(), db, countSql, dataSql, countParams, params, pageId, recPerPage)
 }
 
-func rowsToMap(rows *sql.Rows) ([]map[string]string, error) {
+func rowsToMapLog(rows *sql.Rows, log *MyLogger) ([]map[string]string, error) {
 	cols, _ := rows.Columns()
 	values := make([]sql.RawBytes, len(cols))
 	scans := make([]interface{}, len(cols))
@@ -570,7 +344,7 @@ func rowsToMap(rows *sql.Rows) ([]map[string]string, error) {
 	var results []map[string]string
 	for rows.Next() {
 		if err := rows.Scan(scans...); err != nil {
-			Log.Error("Error: ", err)
+			log.Error("Error: ", err)
 			return nil, err
 		}
 		row := make(map[string]string)
@@ -3,7 +3,9 @@ package commonlib
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"io"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -53,3 +55,23 @@ func GetMd5String(s string) string {
 	h.Write([]byte(s))
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+//计算reader内容的32位md5字串
+func GetMd5Reader(r io.Reader) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+//计算文件的32位md5字串
+func GetFileMd5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return GetMd5Reader(file)
+}
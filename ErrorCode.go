@@ -0,0 +1,158 @@
+package commonlib
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// 内置响应码，各服务自定义的响应码需要使用ServiceCodeMin以上的值
+const (
+	CodeSuccess      = 0
+	CodeFail         = -1
+	CodeParamsError  = 1001
+	CodeDbError      = 2001
+	CodeNotFound     = 3001
+	CodeUnauthorized = 4001
+	CodeSignError    = 4002
+	CodeForbidden    = 4003
+
+	ServiceCodeMin = 10000
+)
+
+// 错误分类
+const (
+	ErrorCategoryCommon   = "common"
+	ErrorCategoryParams   = "params"
+	ErrorCategoryDb       = "db"
+	ErrorCategoryNotFound = "not-found"
+	ErrorCategoryAuth     = "auth"
+)
+
+// 业务错误码定义
+type ErrorCode struct {
+	Code       int    `json:"code"`
+	HttpStatus int    `json:"httpStatus"` // 对应的http状态码
+	Message    string `json:"message"`    // 默认提示信息
+	Category   string `json:"category"`
+}
+
+var (
+	errorCodeLock sync.RWMutex
+	errorCodes    = map[int]ErrorCode{}
+)
+
+func init() {
+	for _, e := range []ErrorCode{
+		{CodeSuccess, http.StatusOK, "成功", ErrorCategoryCommon},
+		{CodeFail, http.StatusBadRequest, "请求处理失败", ErrorCategoryCommon},
+		{CodeParamsError, http.StatusBadRequest, "参数格式异常", ErrorCategoryParams},
+		{CodeDbError, http.StatusInternalServerError, "数据库异常", ErrorCategoryDb},
+		{CodeNotFound, http.StatusNotFound, "对象不存在", ErrorCategoryNotFound},
+		{CodeUnauthorized, http.StatusUnauthorized, "未登录或登录已过期", ErrorCategoryAuth},
+		{CodeSignError, http.StatusUnauthorized, "签名校验失败", ErrorCategoryAuth},
+		{CodeForbidden, http.StatusForbidden, "没有权限", ErrorCategoryAuth},
+	} {
+		errorCodes[e.Code] = e
+	}
+}
+
+/**
+ * 注册业务错误码
+ * @param e	错误码定义，Code必须不小于ServiceCodeMin，HttpStatus为0时使用200
+ *
+ * return 错误信息(响应码已被注册或在保留范围内)
+ *
+ * example:
+ *   var CodeScheduleConflict = MustRegisterErrorCode(ErrorCode{
+ *     Code: 20001, HttpStatus: http.StatusConflict, Message: "课程时间冲突", Category: "schedule",
+ *   })
+ */
+func RegisterErrorCode(e ErrorCode) error {
+	if e.Code < ServiceCodeMin {
+		return fmt.Errorf("响应码%d在保留范围内，自定义响应码需要不小于%d", e.Code, ServiceCodeMin)
+	}
+	if e.HttpStatus == 0 {
+		e.HttpStatus = http.StatusOK
+	}
+
+	errorCodeLock.Lock()
+	defer errorCodeLock.Unlock()
+
+	if exist, ok := errorCodes[e.Code]; ok {
+		return fmt.Errorf("响应码%d已被注册: %s", e.Code, exist.Message)
+	}
+	errorCodes[e.Code] = e
+
+	return nil
+}
+
+// 注册业务错误码，失败时panic，用于包级变量初始化
+func MustRegisterErrorCode(e ErrorCode) int {
+	if err := RegisterErrorCode(e); err != nil {
+		panic(err)
+	}
+	return e.Code
+}
+
+// 查找错误码定义
+func LookupErrorCode(code int) (ErrorCode, bool) {
+	errorCodeLock.RLock()
+	defer errorCodeLock.RUnlock()
+
+	e, ok := errorCodes[code]
+	return e, ok
+}
+
+// 响应码对应的http状态码，未注册的响应码返回400
+func HttpStatusOf(code int) int {
+	if e, ok := LookupErrorCode(code); ok {
+		return e.HttpStatus
+	}
+	return http.StatusBadRequest
+}
+
+// 所有已注册的错误码，按响应码排序
+func ErrorCodes() []ErrorCode {
+	errorCodeLock.RLock()
+	defer errorCodeLock.RUnlock()
+
+	result := make([]ErrorCode, 0, len(errorCodes))
+	for _, e := range errorCodes {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+
+	return result
+}
+
+// 创建带响应码的错误，message为空时使用错误码的默认提示
+func NewCodeError(code int, message string) *ResponseError {
+	return &ResponseError{Code: code, Message: codeMessage(code, message)}
+}
+
+// 错误分类，未注册的响应码返回common
+func (e *ResponseError) Category() string {
+	if code, ok := LookupErrorCode(e.Code); ok {
+		return code.Category
+	}
+	return ErrorCategoryCommon
+}
+
+// 对应的http状态码
+func (e *ResponseError) HttpStatus() int {
+	return HttpStatusOf(e.Code)
+}
+
+func codeMessage(code int, message string) string {
+	if message != "" {
+		return message
+	}
+	if e, ok := LookupErrorCode(code); ok {
+		return e.Message
+	}
+	return ""
+}
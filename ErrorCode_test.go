@@ -0,0 +1,113 @@
+package commonlib
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRegisterErrorCode(t *testing.T) {
+	cases := []struct {
+		name string
+		code ErrorCode
+		ok   bool
+	}{
+		{"保留范围", ErrorCode{Code: CodeNotFound + 1, Message: "保留"}, false},
+		{"内置响应码", ErrorCode{Code: CodeNotFound, Message: "重复"}, false},
+		{"刚好小于最小值", ErrorCode{Code: ServiceCodeMin - 1, Message: "保留"}, false},
+		{"最小值", ErrorCode{Code: ServiceCodeMin, Message: "最小值"}, true},
+		{"自定义", ErrorCode{Code: 99901, HttpStatus: http.StatusConflict, Message: "冲突", Category: "test"}, true},
+		{"重复注册", ErrorCode{Code: 99901, Message: "重复"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := RegisterErrorCode(c.code); (err == nil) != c.ok {
+				t.Errorf("RegisterErrorCode() error = %v", err)
+			}
+		})
+	}
+
+	// 重复注册不覆盖已有定义
+	if e, _ := LookupErrorCode(99901); e.Message != "冲突" || e.Category != "test" {
+		t.Errorf("LookupErrorCode() = %+v", e)
+	}
+	if e, _ := LookupErrorCode(CodeNotFound); e.Message != "对象不存在" {
+		t.Errorf("LookupErrorCode() = %+v", e)
+	}
+}
+
+func TestMustRegisterErrorCode(t *testing.T) {
+	if code := MustRegisterErrorCode(ErrorCode{Code: 99902, Message: "测试"}); code != 99902 {
+		t.Errorf("MustRegisterErrorCode() = %d", code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("重复注册应该panic")
+		}
+	}()
+	MustRegisterErrorCode(ErrorCode{Code: 99902, Message: "测试"})
+}
+
+func TestHttpStatusOf(t *testing.T) {
+	MustRegisterErrorCode(ErrorCode{Code: 99903, HttpStatus: http.StatusConflict, Message: "冲突"})
+	MustRegisterErrorCode(ErrorCode{Code: 99904, Message: "默认状态码"})
+
+	cases := []struct {
+		name     string
+		code     int
+		expected int
+	}{
+		{"成功", CodeSuccess, http.StatusOK},
+		{"失败", CodeFail, http.StatusBadRequest},
+		{"参数错误", CodeParamsError, http.StatusBadRequest},
+		{"数据库异常", CodeDbError, http.StatusInternalServerError},
+		{"对象不存在", CodeNotFound, http.StatusNotFound},
+		{"未登录", CodeUnauthorized, http.StatusUnauthorized},
+		{"签名错误", CodeSignError, http.StatusUnauthorized},
+		{"没有权限", CodeForbidden, http.StatusForbidden},
+		{"自定义", 99903, http.StatusConflict},
+		{"自定义未设置状态码", 99904, http.StatusOK},
+		{"未注册", 88888, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if status := HttpStatusOf(c.code); status != c.expected {
+				t.Errorf("HttpStatusOf(%d) = %d, expected %d", c.code, status, c.expected)
+			}
+		})
+	}
+}
+
+func TestCodeMessage(t *testing.T) {
+	MustRegisterErrorCode(ErrorCode{Code: 99905, Message: "自定义提示"})
+
+	cases := []struct {
+		name     string
+		code     int
+		message  string
+		expected string
+	}{
+		{"内置默认提示", CodeDbError, "", "数据库异常"},
+		{"自定义默认提示", 99905, "", "自定义提示"},
+		{"指定提示", CodeDbError, "连接失败", "连接失败"},
+		{"未注册", 88888, "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := NewCodeError(c.code, c.message)
+			if err.Message != c.expected {
+				t.Errorf("NewCodeError() message = %s, expected %s", err.Message, c.expected)
+			}
+		})
+	}
+
+	if category := NewCodeError(88888, "").Category(); category != ErrorCategoryCommon {
+		t.Errorf("Category() = %s", category)
+	}
+	if category := NewCodeError(CodeSignError, "").Category(); category != ErrorCategoryAuth {
+		t.Errorf("Category() = %s", category)
+	}
+}
@@ -0,0 +1,446 @@
+package commonlib
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缓存的响应
+type CachedResponse struct {
+	Status   int               `json:"status"`
+	Header   http.Header       `json:"header"`
+	Body     []byte            `json:"body"`
+	Vary     map[string]string `json:"vary,omitempty"` // 响应头Vary中列出的请求头取值
+	StoredAt time.Time         `json:"storedAt"`
+}
+
+// 响应缓存存储
+type HttpCacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, res *CachedResponse)
+	Delete(key string)
+}
+
+/**
+ * GET请求响应缓存中间件，遵循响应头Cache-Control/Expires判断是否过期，
+ * 过期后使用ETag/Last-Modified发送条件请求，服务端返回304时继续使用缓存内容
+ *
+ * 请求头Cache-Control为no-cache/no-store时不使用缓存，也可以使用WithoutHttpCache(ctx)跳过缓存
+ * 带有Authorization/Cookie的请求及Cache-Control为private的响应不使用缓存，避免不同用户之间共享响应
+ *
+ * example:
+ *   cache := NewHttpCache(NewMemoryCacheStore(1000))
+ *   client.Use(cache.Middleware())
+ */
+type HttpCache struct {
+	Store HttpCacheStore
+}
+
+func NewHttpCache(store HttpCacheStore) *HttpCache {
+	return &HttpCache{Store: store}
+}
+
+type httpCacheBypassKey struct{}
+
+// 返回跳过缓存的context，用于单次请求不使用缓存
+func WithoutHttpCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, httpCacheBypassKey{}, true)
+}
+
+func (c *HttpCache) Middleware() HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Method != "GET" || c.bypass(request) {
+				return next.RoundTrip(request)
+			}
+
+			key := request.URL.String()
+			cached, ok := c.Store.Get(key)
+			if ok && !cached.matchVary(request) {
+				ok = false
+			}
+
+			if ok && cached.fresh(time.Now()) {
+				return cached.response(request, "HIT"), nil
+			}
+
+			if ok {
+				// 已过期，使用ETag/Last-Modified发送条件请求
+				request = request.Clone(request.Context())
+				if etag := cached.Header.Get("ETag"); etag != "" {
+					request.Header.Set("If-None-Match", etag)
+				}
+				if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+					request.Header.Set("If-Modified-Since", lastModified)
+				}
+			}
+
+			resp, err := next.RoundTrip(request)
+			if err != nil {
+				return resp, err
+			}
+
+			if ok && resp.StatusCode == http.StatusNotModified {
+				resp.Body.Close()
+				for name, values := range resp.Header {
+					cached.Header[name] = values
+				}
+				cached.StoredAt = time.Now()
+				c.Store.Set(key, cached)
+				return cached.response(request, "REVALIDATED"), nil
+			}
+
+			if resp.StatusCode != http.StatusOK || !storable(resp) {
+				if ok {
+					c.Store.Delete(key)
+				}
+				return resp, nil
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			c.Store.Set(key, &CachedResponse{
+				Status:   resp.StatusCode,
+				Header:   resp.Header.Clone(),
+				Body:     body,
+				Vary:     varyValues(resp, request),
+				StoredAt: time.Now(),
+			})
+			resp.Header.Set("X-Cache", "MISS")
+
+			return resp, nil
+		})
+	}
+}
+
+func (c *HttpCache) bypass(request *http.Request) bool {
+	if bypass, _ := request.Context().Value(httpCacheBypassKey{}).(bool); bypass {
+		return true
+	}
+
+	// 默认请求头中的max-age=0不视为跳过缓存
+	directives := parseCacheControl(request.Header.Get("Cache-Control"))
+	_, noCache := directives["no-cache"]
+	_, noStore := directives["no-store"]
+
+	if noCache || noStore || request.Header.Get("Range") != "" {
+		return true
+	}
+
+	// 缓存键不包含用户身份，带有身份信息的请求不使用缓存
+	return request.Header.Get("Authorization") != "" || request.Header.Get("Cookie") != ""
+}
+
+// 响应是否可以缓存，private及设置Cookie的响应只属于当前用户
+func storable(resp *http.Response) bool {
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	if _, ok := directives["private"]; ok {
+		return false
+	}
+	if resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	return resp.Header.Get("Vary") != "*"
+}
+
+// 是否在有效期内
+func (res *CachedResponse) fresh(now time.Time) bool {
+	directives := parseCacheControl(res.Header.Get("Cache-Control"))
+	if _, ok := directives["no-cache"]; ok {
+		return false
+	}
+
+	age := now.Sub(res.StoredAt)
+
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		return err == nil && age < time.Duration(seconds)*time.Second
+	}
+
+	if expires := res.Header.Get("Expires"); expires != "" {
+		expireAt, err := http.ParseTime(expires)
+		if err != nil {
+			return false
+		}
+		if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+			// 使用服务端时间计算有效期，避免本地时钟偏差
+			return age < expireAt.Sub(date)
+		}
+		return now.Before(expireAt)
+	}
+
+	return false
+}
+
+func (res *CachedResponse) matchVary(request *http.Request) bool {
+	for key, val := range res.Vary {
+		if request.Header.Get(key) != val {
+			return false
+		}
+	}
+	return true
+}
+
+func (res *CachedResponse) response(request *http.Request, status string) *http.Response {
+	header := res.Header.Clone()
+	header.Set("X-Cache", status)
+
+	return &http.Response{
+		Status:        http.StatusText(res.Status),
+		StatusCode:    res.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(res.Body)),
+		ContentLength: int64(len(res.Body)),
+		Request:       request,
+	}
+}
+
+func varyValues(resp *http.Response, request *http.Request) map[string]string {
+	vary := resp.Header.Get("Vary")
+	if vary == "" {
+		return nil
+	}
+
+	values := make(map[string]string)
+	for _, key := range strings.Split(vary, ",") {
+		key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+		if key != "" {
+			values[key] = request.Header.Get(key)
+		}
+	}
+
+	return values
+}
+
+func parseCacheControl(cacheControl string) map[string]string {
+	directives := make(map[string]string)
+
+	for _, part := range strings.Split(cacheControl, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if index := strings.Index(part, "="); index >= 0 {
+			directives[strings.ToLower(part[:index])] = strings.Trim(part[index+1:], `"`)
+		} else {
+			directives[strings.ToLower(part)] = ""
+		}
+	}
+
+	return directives
+}
+
+// 基于内存的LRU缓存
+type MemoryCacheStore struct {
+	MaxEntries int
+
+	lock  sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+type memoryCacheItem struct {
+	key string
+	res *CachedResponse
+}
+
+// 创建内存缓存，maxEntries为最多缓存的响应数，超出时淘汰最久未使用的响应
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		MaxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	element, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+
+	res := *element.Value.(*memoryCacheItem).res
+	res.Header = res.Header.Clone()
+
+	return &res, true
+}
+
+func (s *MemoryCacheStore) Set(key string, res *CachedResponse) {
+	// 保存副本，调用方之后修改res不影响缓存内容
+	copied := *res
+	copied.Header = res.Header.Clone()
+	res = &copied
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		element.Value.(*memoryCacheItem).res = res
+		s.order.MoveToFront(element)
+		return
+	}
+
+	s.items[key] = s.order.PushFront(&memoryCacheItem{key: key, res: res})
+
+	for s.MaxEntries > 0 && s.order.Len() > s.MaxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (s *MemoryCacheStore) Delete(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		s.order.Remove(element)
+		delete(s.items, key)
+	}
+}
+
+// 文件缓存默认的最大容量
+const DefaultDiskCacheSize = 100 << 20
+
+// 基于文件的缓存，每个响应保存为目录下的一个文件
+type DiskCacheStore struct {
+	Dir string
+
+	// 缓存文件的总字节数上限，超出时删除最久未使用的文件，0表示不限制
+	MaxSize int64
+
+	lock sync.Mutex
+}
+
+// 创建文件缓存，最大容量为DefaultDiskCacheSize，可以修改MaxSize
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheStore{Dir: dir, MaxSize: DefaultDiskCacheSize}, nil
+}
+
+func (s *DiskCacheStore) path(key string) string {
+	return filepath.Join(s.Dir, GetMd5String(key)+".json")
+}
+
+func (s *DiskCacheStore) Get(key string) (*CachedResponse, bool) {
+	path := s.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	// 修改时间作为最近使用时间，超出容量时按此淘汰
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	res := new(CachedResponse)
+	if err = json.Unmarshal(data, res); err != nil {
+		Log.Warn("读取http缓存文件发生错误:", err)
+		return nil, false
+	}
+
+	return res, true
+}
+
+func (s *DiskCacheStore) Set(key string, res *CachedResponse) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		Log.Warn("保存http缓存发生错误:", err)
+		return
+	}
+
+	// 同一个key可能被同时保存，每次使用不同的临时文件
+	temp, err := ioutil.TempFile(s.Dir, "cache-*.tmp")
+	if err != nil {
+		Log.Warn("保存http缓存发生错误:", err)
+		return
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), s.path(key))
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		Log.Warn("保存http缓存发生错误:", err)
+		return
+	}
+
+	s.trim()
+}
+
+// 缓存文件超出MaxSize时删除最久未使用的文件
+func (s *DiskCacheStore) trim() {
+	if s.MaxSize <= 0 {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return
+	}
+
+	var total int64
+	entries := make([]os.FileInfo, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		total += file.Size()
+		entries = append(entries, file)
+	}
+	if total <= s.MaxSize {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+	for _, file := range entries {
+		if total <= s.MaxSize {
+			break
+		}
+		if os.Remove(filepath.Join(s.Dir, file.Name())) == nil {
+			total -= file.Size()
+		}
+	}
+}
+
+func (s *DiskCacheStore) Delete(key string) {
+	os.Remove(s.path(key))
+}
@@ -0,0 +1,108 @@
+package commonlib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 返回固定响应并统计请求数
+func countingTransport(header http.Header, count *int) http.RoundTripper {
+	return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		*count++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     header.Clone(),
+			Body:       ioutil.NopCloser(strings.NewReader("user data")),
+			Request:    request,
+		}, nil
+	})
+}
+
+func TestHttpCacheCredentials(t *testing.T) {
+	cases := []struct {
+		name   string
+		header http.Header // 请求头
+		resp   http.Header // 响应头
+		cached bool
+	}{
+		{"公共响应", http.Header{}, http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{"Authorization", http.Header{"Authorization": {"Bearer a"}}, http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{"Cookie", http.Header{"Cookie": {"session=a"}}, http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{"private", http.Header{}, http.Header{"Cache-Control": {"private, max-age=60"}}, false},
+		{"Set-Cookie", http.Header{}, http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"session=a"}}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			count := 0
+			transport := NewHttpCache(NewMemoryCacheStore(10)).Middleware()(countingTransport(c.resp, &count))
+
+			for i := 0; i < 2; i++ {
+				request, _ := http.NewRequest("GET", "http://example.com/profile", nil)
+				request.Header = c.header.Clone()
+				resp, err := transport.RoundTrip(request)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+
+			if cached := count == 1; cached != c.cached {
+				t.Errorf("请求次数 %d, cached %v", count, c.cached)
+			}
+		})
+	}
+}
+
+func TestDiskCacheStoreMaxSize(t *testing.T) {
+	store, err := NewDiskCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.MaxSize = 1000
+
+	body := []byte(strings.Repeat("a", 300))
+	for _, key := range []string{"k1", "k2", "k3"} {
+		store.Set(key, &CachedResponse{Status: 200, Header: http.Header{}, Body: body, StoredAt: time.Now()})
+		// 修改时间的精度可能较低
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if _, ok := store.Get("k1"); ok {
+		t.Error("超出容量时应该删除最久未使用的缓存")
+	}
+	if _, ok := store.Get("k3"); !ok {
+		t.Error("最新的缓存不应该被删除")
+	}
+}
+
+func TestDiskCacheStoreConcurrentSet(t *testing.T) {
+	store, err := NewDiskCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Set("key", &CachedResponse{Status: 200, Header: http.Header{}, Body: []byte("body"), StoredAt: time.Now()})
+		}()
+	}
+	wg.Wait()
+
+	res, ok := store.Get("key")
+	if !ok || string(res.Body) != "body" {
+		t.Errorf("Get() = %v, %v", res, ok)
+	}
+
+	files, _ := ioutil.ReadDir(store.Dir)
+	if len(files) != 1 {
+		t.Errorf("缓存目录中有 %d 个文件, 临时文件应该被重命名或删除", len(files))
+	}
+}
@@ -0,0 +1,342 @@
+package commonlib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// cassette模式
+const (
+	CassetteRecord = "record" // 发送真实请求并保存请求和响应
+	CassetteReplay = "replay" // 只从文件中返回响应，不发送真实请求
+)
+
+// 隐藏后的取值
+const cassetteRedacted = "REDACTED"
+
+/**
+ * http请求录制/回放，用于在没有网络的环境下测试调用第三方接口的代码
+ *
+ * example:
+ *   cassette, err := NewCassette("fixtures/schedule.json", CassetteReplay)
+ *   client := NewHttpClient()
+ *   client.Use(cassette.Middleware())
+ *   body, err := client.Get("http://example.com/list")
+ *
+ * 录制时将模式改为CassetteRecord运行一次，文件中的记录会被追加保存
+ *
+ * 录制模式及设置了MatchBody的回放模式会将请求体整体读入内存，
+ * 流式上传(PostMultipart)大文件时只在MatchBody为false的回放模式下不占用内存
+ */
+type Cassette struct {
+	Path string
+	Mode string
+
+	// 匹配请求时是否比较请求体，方法和url总是参与比较，为true时请求体会整体读入内存
+	MatchBody bool
+
+	// 匹配请求时需要比较的请求头
+	MatchHeaders []string
+
+	// 保存时隐藏取值的请求头/响应头
+	RedactHeaders []string
+
+	// 保存时隐藏取值的url参数/表单参数，匹配时忽略这些参数的取值(如签名、时间戳、nonce)
+	RedactParams []string
+
+	lock         sync.Mutex
+	interactions []*CassetteInteraction
+	used         []bool
+}
+
+// 一次请求和响应的记录
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method       string      `json:"method"`
+	Url          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"` // 为base64时Body为base64编码的二进制内容
+}
+
+type CassetteResponse struct {
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+}
+
+/**
+ * 创建cassette并加载已有的记录
+ * @param path	记录文件路径
+ * @param mode	CassetteRecord 或 CassetteReplay
+ *
+ * return cassette， 错误信息(回放模式下文件不存在等)
+ */
+func NewCassette(path, mode string) (*Cassette, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, errors.New("cassette模式错误: " + mode)
+	}
+
+	c := &Cassette{
+		Path:          path,
+		Mode:          mode,
+		RedactHeaders: []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"},
+		RedactParams:  []string{SignParamSign, SignParamNonce, SignParamTimestamp, "token", "access_token", "password", "secret"},
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if mode == CassetteRecord && os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, errors.New("cassette文件格式错误: " + err.Error())
+	}
+	c.used = make([]bool, len(c.interactions))
+
+	return c, nil
+}
+
+// 返回录制/回放中间件
+func (c *Cassette) Middleware() HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			// 回放时不比较请求体则不需要读取，直接关闭以结束流式上传的写入
+			if c.Mode == CassetteReplay && !c.MatchBody {
+				if request.Body != nil {
+					request.Body.Close()
+				}
+				return c.replay(request, c.redactRequest(request, nil))
+			}
+
+			var body []byte
+			if request.Body != nil {
+				var err error
+				body, err = ioutil.ReadAll(request.Body)
+				request.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				request = request.Clone(request.Context())
+				request.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+
+			recorded := c.redactRequest(request, body)
+
+			if c.Mode == CassetteReplay {
+				return c.replay(request, recorded)
+			}
+
+			return c.record(next, request, recorded)
+		})
+	}
+}
+
+func (c *Cassette) replay(request *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// 优先使用未回放过的记录，都回放过时使用最后一条匹配的记录
+	found := -1
+	for i, interaction := range c.interactions {
+		if !c.match(&interaction.Request, &recorded) {
+			continue
+		}
+		found = i
+		if !c.used[i] {
+			break
+		}
+	}
+
+	if found < 0 {
+		err := errors.New("cassette中没有匹配的请求: " + recorded.Method + " " + recorded.Url)
+		LogFrom(request.Context()).Error(err)
+		return nil, err
+	}
+	c.used[found] = true
+
+	res := c.interactions[found].Response
+	body, err := decodeCassetteBody(res.Body, res.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(res.Status),
+		StatusCode:    res.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        res.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+func (c *Cassette) record(next http.RoundTripper, request *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	resp, err := next.RoundTrip(request)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	c.redactHeader(header)
+
+	interaction := &CassetteInteraction{
+		Request: recorded,
+		Response: CassetteResponse{
+			Status: resp.StatusCode,
+			Header: header,
+		},
+	}
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeCassetteBody(body)
+
+	c.lock.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+	err = c.save()
+	c.lock.Unlock()
+
+	if err != nil {
+		LogFrom(request.Context()).Error("保存cassette发生错误:", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// 写入临时文件后重命名，避免中途失败损坏已有记录
+func (c *Cassette) save() error {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(c.interactions); err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(c.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tempPath := c.Path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, c.Path)
+}
+
+func (c *Cassette) match(saved, req *CassetteRequest) bool {
+	if saved.Method != req.Method || saved.Url != req.Url {
+		return false
+	}
+
+	if c.MatchBody && (saved.Body != req.Body || saved.BodyEncoding != req.BodyEncoding) {
+		return false
+	}
+
+	for _, key := range c.MatchHeaders {
+		if saved.Header.Get(key) != req.Header.Get(key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Cassette) redactRequest(request *http.Request, body []byte) CassetteRequest {
+	u := *request.URL
+	u.RawQuery = c.redactValues(u.RawQuery)
+
+	header := request.Header.Clone()
+	c.redactHeader(header)
+	if referer, err := url.Parse(header.Get("Referer")); err == nil && referer.RawQuery != "" {
+		referer.RawQuery = c.redactValues(referer.RawQuery)
+		header.Set("Referer", referer.String())
+	}
+
+	recorded := CassetteRequest{
+		Method: request.Method,
+		Url:    u.String(),
+		Header: header,
+	}
+
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "application/x-www-form-urlencoded") &&
+		request.Header.Get("Content-Encoding") == "" {
+		body = []byte(c.redactValues(string(body)))
+	}
+	recorded.Body, recorded.BodyEncoding = encodeCassetteBody(body)
+
+	return recorded
+}
+
+func (c *Cassette) redactHeader(header http.Header) {
+	for _, key := range c.RedactHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, cassetteRedacted)
+		}
+	}
+}
+
+// 隐藏url参数/表单参数的取值，参数按名称排序以便比较
+func (c *Cassette) redactValues(query string) string {
+	if query == "" {
+		return query
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+
+	for _, key := range c.RedactParams {
+		if _, ok := values[key]; ok {
+			values.Set(key, cassetteRedacted)
+		}
+	}
+
+	return values.Encode()
+}
+
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeCassetteBody(body, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
@@ -0,0 +1,129 @@
+package commonlib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		r.ParseForm()
+		w.Header().Set("X-Count", string(rune('0'+n)))
+		w.Write([]byte(r.URL.Path + ":" + r.Form.Get("name") + ":" + string(rune('0'+n))))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.MatchBody = true
+	client := NewHttpClient()
+	client.Use(recorder.Middleware())
+
+	recorded := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{"GET", server.URL + "/list?timestamp=1&sign=a", ""},
+		{"GET", server.URL + "/list?timestamp=2&sign=b", ""},
+		{"POST", server.URL + "/add", "name=a&nonce=1"},
+		{"POST", server.URL + "/add", "name=b&nonce=2"},
+	}
+	for _, r := range recorded {
+		if r.method == "GET" {
+			_, err = client.Get(r.url)
+		} else {
+			_, err = client.Post(r.url, r.body)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "sign=a") || strings.Contains(string(data), "nonce=1") {
+		t.Errorf("签名参数没有隐藏: %s", data)
+	}
+
+	cases := []struct {
+		name      string
+		matchBody bool
+		method    string
+		url       string
+		body      string
+		expected  string // 为空时期望没有匹配的记录
+	}{
+		// 隐藏的参数取值不参与匹配，相同的请求按录制顺序回放
+		{"第一次", false, "GET", "/list?timestamp=9&sign=x", "", "/list::1"},
+		{"第二次", false, "GET", "/list?sign=y&timestamp=8", "", "/list::2"},
+		{"全部回放过时使用最后一条", false, "GET", "/list?timestamp=7&sign=z", "", "/list::2"},
+		{"比较请求体", true, "POST", "/add", "name=b&nonce=9", "/add:b:4"},
+		{"请求体不匹配", true, "POST", "/add", "name=c&nonce=9", ""},
+		{"不比较请求体", false, "POST", "/add", "name=c", "/add:a:3"},
+		{"url不匹配", false, "GET", "/other", "", ""},
+	}
+
+	replayer, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = NewHttpClient()
+	client.Use(replayer.Middleware())
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			replayer.MatchBody = c.matchBody
+
+			var body []byte
+			if c.method == "GET" {
+				body, err = client.Get(server.URL + c.url)
+			} else {
+				body, err = client.Post(server.URL+c.url, c.body)
+			}
+			if c.expected == "" {
+				if err == nil {
+					t.Errorf("应该没有匹配的记录, body = %s", body)
+				}
+				return
+			}
+			if err != nil || string(body) != c.expected {
+				t.Errorf("body = %s, error = %v, expected %s", body, err, c.expected)
+			}
+		})
+	}
+
+	if atomic.LoadInt32(&requests) != int32(len(recorded)) {
+		t.Errorf("回放时不应该发送真实请求, 请求次数 %d", requests)
+	}
+}
+
+func TestCassetteReplayStreaming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ioutil.WriteFile(path, []byte(`[{"request":{"method":"POST","url":"http://example.com/upload"},"response":{"status":200,"body":"ok"}}]`), 0644)
+
+	cassette, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewHttpClient()
+	client.Use(cassette.Middleware())
+
+	form := NewMultipartForm()
+	form.AddReader("file", "a.txt", "", strings.NewReader(strings.Repeat("a", 1<<20)))
+
+	body, err := client.PostMultipart("http://example.com/upload", form)
+	if err != nil || string(body) != "ok" {
+		t.Errorf("PostMultipart() = %s, %v", body, err)
+	}
+}
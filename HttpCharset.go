@@ -0,0 +1,101 @@
+package commonlib
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	metaCharsetReg = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_\-:.]+)`)
+	xmlCharsetReg  = regexp.MustCompile(`(?i)<\?xml[^>]+encoding\s*=\s*["']([a-z0-9_\-:.]+)["']`)
+)
+
+// 识别字符集时最多扫描的响应体长度
+const charsetSniffLen = 4096
+
+/**
+ * 识别响应体字符集
+ * @param contentType	响应头Content-Type
+ * @param body		响应体
+ *
+ * return 字符集名称(小写)，无法识别时返回空字符串
+ *
+ * 识别顺序: BOM > Content-Type > html meta标签 > xml声明
+ */
+func DetectCharset(contentType string, body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return "utf-16be"
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return "utf-16le"
+	}
+
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+			return strings.ToLower(strings.Trim(params["charset"], `"' `))
+		}
+	}
+
+	head := body
+	if len(head) > charsetSniffLen {
+		head = head[:charsetSniffLen]
+	}
+
+	if m := metaCharsetReg.FindSubmatch(head); m != nil {
+		return strings.ToLower(string(m[1]))
+	}
+	if m := xmlCharsetReg.FindSubmatch(head); m != nil {
+		return strings.ToLower(string(m[1]))
+	}
+
+	return ""
+}
+
+/**
+ * 将响应体转换为UTF-8
+ * @param body		响应体
+ * @param contentType	响应头Content-Type
+ * @param charset	指定字符集，为空时自动识别
+ *
+ * return 转换后的内容， 错误信息
+ *
+ * 无法识别字符集且内容不是合法的UTF-8时，按GB18030(兼容GBK/GB2312)处理
+ */
+func ConvertToUTF8(body []byte, contentType, charset string) ([]byte, error) {
+	if charset == "" {
+		charset = DetectCharset(contentType, body)
+	}
+
+	if charset == "" {
+		if utf8.Valid(body) {
+			return body, nil
+		}
+		charset = "gb18030"
+	}
+
+	charset = strings.ToLower(charset)
+	if charset == "utf-8" || charset == "utf8" {
+		return bytes.TrimPrefix(body, []byte{0xEF, 0xBB, 0xBF}), nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, errors.New("不支持的字符集: " + charset)
+	}
+
+	result, _, err := transform.Bytes(encoding.NewDecoder(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// utf-16的BOM解码后为U+FEFF，与utf-8一致去掉
+	return bytes.TrimPrefix(result, []byte("\uFEFF")), nil
+}
@@ -0,0 +1,101 @@
+package commonlib
+
+import (
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	meta := `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gb2312"></head></html>`
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"BOM优先于响应头", "text/html; charset=gbk", bom + meta, "utf-8"},
+		{"utf-16le BOM", "text/html; charset=gbk", "\xFF\xFEa\x00", "utf-16le"},
+		{"utf-16be BOM", "", "\xFE\xFF\x00a", "utf-16be"},
+		{"响应头优先于meta", "text/html; charset=UTF-8", meta, "utf-8"},
+		{"响应头带引号", `text/html; charset="GBK"`, "", "gbk"},
+		{"响应头没有charset时使用meta", "text/html", meta, "gb2312"},
+		{"html5 meta", "", `<meta charset='GBK'>`, "gbk"},
+		{"xml声明", "text/xml", `<?xml version="1.0" encoding="GB18030"?><a/>`, "gb18030"},
+		{"meta优先于xml声明", "", `<?xml version="1.0" encoding="gb18030"?><meta charset="big5">`, "big5"},
+		{"无法识别", "text/plain", "abc", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if charset := DetectCharset(c.contentType, []byte(c.body)); charset != c.expected {
+				t.Errorf("DetectCharset() = %s, expected %s", charset, c.expected)
+			}
+		})
+	}
+}
+
+func TestConvertToUTF8(t *testing.T) {
+	text := "中文内容"
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String(text)
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(text)
+
+	cases := []struct {
+		name        string
+		contentType string
+		charset     string
+		body        string
+		expected    string
+		ok          bool
+	}{
+		{"响应头指定gbk", "text/html; charset=gbk", "", gbk, text, true},
+		{"指定字符集优先", "text/html; charset=utf-8", "GBK", gbk, text, true},
+		{"未识别且不是utf-8时按gb18030", "", "", gbk, text, true},
+		{"未识别的utf-8原样返回", "", "", text, text, true},
+		{"去掉utf-8 BOM", "text/html; charset=gbk", "", "\xEF\xBB\xBF" + text, text, true},
+		{"utf-16 BOM", "", "", utf16, text, true},
+		{"不支持的字符集", "text/html; charset=unknown-charset", "", text, "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := ConvertToUTF8([]byte(c.body), c.contentType, c.charset)
+			if !c.ok {
+				if err == nil {
+					t.Errorf("应该返回错误, result = %s", result)
+				}
+				return
+			}
+			if err != nil || string(result) != c.expected {
+				t.Errorf("ConvertToUTF8() = %q, error = %v, expected %s", result, err, c.expected)
+			}
+		})
+	}
+}
+
+func TestHttpClientConvertCharset(t *testing.T) {
+	text := "中文内容"
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String(text)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<meta charset="gbk">` + gbk))
+	}))
+	defer server.Close()
+
+	client := NewHttpClient()
+	body, err := client.Get(server.URL)
+	if err != nil || string(body) == `<meta charset="gbk">`+text {
+		t.Errorf("没有设置ConvertCharset时不应该转换, body = %q, error = %v", body, err)
+	}
+
+	client.ConvertCharset = true
+	body, err = client.Get(server.URL)
+	if err != nil || string(body) != `<meta charset="gbk">`+text {
+		t.Errorf("Get() = %q, error = %v", body, err)
+	}
+}
@@ -0,0 +1,255 @@
+package commonlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 文件下载选项
+type DownloadOptions struct {
+	// 下载进度回调，可以为nil
+	Progress ProgressFunc
+
+	// 期望的文件md5(32位小写)，为空时不校验
+	Md5 string
+
+	// 存在未完成的临时文件时，是否使用Range从断点继续下载
+	Resume bool
+}
+
+// 下载未完成时使用的临时文件后缀，下载完成并校验通过后重命名为目标文件
+const downloadTempSuffix = ".download"
+
+// 保存临时文件对应的ETag/Last-Modified，续传时作为If-Range，远程文件变化时重新下载
+const downloadValidatorSuffix = ".download.validator"
+
+/**
+ * 下载内容并以流的方式写入writer
+ * @param url		下载地址
+ * @param w		写入目标
+ * @param progress	下载进度回调，可以为nil
+ *
+ * return 写入的字节数， 错误信息
+ */
+func (c *HttpClient) Download(url string, w io.Writer, progress ProgressFunc) (int64, error) {
+	return c.DownloadContext(context.Background(), url, w, progress)
+}
+
+// 带context的下载，ctx用于超时、取消及日志字段，见Download
+func (c *HttpClient) DownloadContext(ctx context.Context, url string, w io.Writer, progress ProgressFunc) (int64, error) {
+	log := LogFrom(ctx)
+	log.Trace("Http Download:" + url)
+
+	resp, body, err := c.openDownload(ctx, url, 0, "")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	defer body.Close()
+
+	n, err := copyWithProgress(w, body, 0, resp.ContentLength, progress)
+	if err != nil {
+		log.Error("Http Download:", url, "发生错误:", err)
+	}
+
+	return n, err
+}
+
+/**
+ * 下载文件到指定路径
+ * @param url	下载地址
+ * @param path	保存路径
+ * @param opts	下载选项，可以为nil
+ *
+ * return 错误信息
+ *
+ * 下载过程中内容写入 path + ".download"，完成并校验通过后才重命名为path，
+ * 因此path要么不存在，要么是完整的文件
+ */
+func (c *HttpClient) DownloadFile(url, path string, opts *DownloadOptions) error {
+	return c.DownloadFileContext(context.Background(), url, path, opts)
+}
+
+// 带context的文件下载，ctx用于超时、取消及日志字段，见DownloadFile
+func (c *HttpClient) DownloadFileContext(ctx context.Context, url, path string, opts *DownloadOptions) error {
+	log := LogFrom(ctx)
+	log.Trace("Http Download:" + url + ",path:" + path)
+
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+
+	tempPath := path + downloadTempSuffix
+	validatorPath := path + downloadValidatorSuffix
+
+	// 没有保存ETag/Last-Modified时无法确认远程文件未变化，不续传
+	var offset int64
+	var validator string
+	if opts.Resume {
+		if data, err := ioutil.ReadFile(validatorPath); err == nil {
+			validator = strings.TrimSpace(string(data))
+		}
+		if info, err := os.Stat(tempPath); err == nil && validator != "" {
+			offset = info.Size()
+		}
+	}
+
+	resp, body, err := c.openDownload(ctx, url, offset, validator)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	defer body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+		flag |= os.O_APPEND
+	} else {
+		// 服务端不支持Range或远程文件已变化(If-Range不匹配)时返回完整内容，重新下载
+		offset = 0
+		flag |= os.O_TRUNC
+
+		os.Remove(validatorPath)
+		if validator = downloadValidator(resp); validator != "" {
+			ioutil.WriteFile(validatorPath, []byte(validator), 0644)
+		}
+	}
+
+	file, err := os.OpenFile(tempPath, flag, 0644)
+	if err != nil {
+		log.Error("Http Download:", url, "发生错误:", err)
+		return err
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	_, err = copyWithProgress(file, body, offset, total, opts.Progress)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// 保留临时文件用于断点续传
+		log.Error("Http Download:", url, "发生错误:", err)
+		return err
+	}
+
+	if opts.Md5 != "" {
+		sum, err := GetFileMd5(tempPath)
+		if err != nil {
+			log.Error("Http Download:", url, "发生错误:", err)
+			return err
+		}
+		if !strings.EqualFold(sum, opts.Md5) {
+			os.Remove(tempPath)
+			os.Remove(validatorPath)
+			err = errors.New("文件校验失败, 期望md5: " + opts.Md5 + ", 实际md5: " + sum)
+			log.Error("Http Download:", url, "发生错误:", err)
+			return err
+		}
+	}
+
+	if err = os.Rename(tempPath, path); err != nil {
+		log.Error("Http Download:", url, "发生错误:", err)
+		return err
+	}
+	os.Remove(validatorPath)
+
+	return nil
+}
+
+// 用于If-Range的校验值，弱ETag不能用于If-Range，此时使用Last-Modified
+func downloadValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+/**
+ * 发送下载请求
+ * @param offset	大于0时请求从该位置开始的内容
+ * @param ifRange	首次下载时响应的ETag或Last-Modified，远程文件已变化时服务端返回完整内容
+ */
+func (c *HttpClient) openDownload(ctx context.Context, url string, offset int64, ifRange string) (*http.Response, io.ReadCloser, error) {
+	log := LogFrom(ctx)
+
+	request, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		log.Error("Http Download:", url, "发生错误:", err)
+		return nil, nil, err
+	}
+
+	// Range针对的是编码后的内容，下载时不使用压缩
+	request.Header.Set("Accept-Encoding", "identity")
+	request.Header.Del("Content-Type")
+	if offset > 0 {
+		request.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		if ifRange != "" {
+			request.Header.Set("If-Range", ifRange)
+		}
+	}
+
+	resp, err := c.httpClient().Do(request.WithContext(ctx))
+	if err != nil {
+		log.Error("Http Download:", url, "发生错误:", err)
+		return nil, nil, err
+	}
+
+	if offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// 临时文件可能已经损坏或超出文件长度，重新完整下载
+		resp.Body.Close()
+		return c.openDownload(ctx, url, 0, "")
+	}
+
+	if resp.StatusCode == http.StatusPartialContent && !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+		resp.Body.Close()
+		err = errors.New("下载失败, 服务端返回的Content-Range不正确: " + resp.Header.Get("Content-Range"))
+		log.Error("Http Download:", url, "发生错误:", err)
+		return nil, nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		err = errors.New("下载失败, 状态码: " + strconv.Itoa(resp.StatusCode))
+		log.Error("Http Download:", url, "发生错误:", err)
+		return nil, nil, err
+	}
+
+	body, err := decodeContent(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		resp.Body.Close()
+		log.Error("Http Download:", url, "发生错误:", err)
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+func copyWithProgress(w io.Writer, r io.Reader, offset, total int64, progress ProgressFunc) (int64, error) {
+	if progress == nil {
+		return io.Copy(w, r)
+	}
+
+	reader := &progressReader{ReadCloser: ioutil.NopCloser(r), sent: offset, total: total, progress: progress}
+	n, err := io.Copy(w, reader)
+
+	return n, err
+}
+
+func HttpDownload(url string, w io.Writer, progress ProgressFunc) (int64, error) {
+	return DefaultHttpClient.Download(url, w, progress)
+}
+
+func HttpDownloadFile(url, path string, opts *DownloadOptions) error {
+	return DefaultHttpClient.DownloadFile(url, path, opts)
+}
@@ -0,0 +1,97 @@
+package commonlib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadFile(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+	md5 := GetMd5String(string(content))
+
+	var lock sync.Mutex
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		headers = append(headers, r.Header.Clone())
+		lock.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name      string
+		temp      []byte // 已下载的临时文件内容，为nil时不存在
+		validator string // 临时文件对应的ETag
+		md5       string
+		valid     bool
+		ranges    []string // 各次请求的Range
+	}{
+		{"全新下载", nil, "", md5, true, []string{""}},
+		{"断点续传", content[:3000], `"v1"`, md5, true, []string{"bytes=3000-"}},
+		{"没有ETag时不续传", content[:3000], "", md5, true, []string{""}},
+		{"远程文件已变化", []byte("old content"), `"v0"`, md5, true, []string{"bytes=11-"}},
+		{"临时文件超出长度", append(append([]byte{}, content...), 'x'), `"v1"`, md5, true, []string{"bytes=10001-", ""}},
+		{"md5不一致", nil, "", GetMd5String("other"), false, []string{""}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lock.Lock()
+			headers = nil
+			lock.Unlock()
+
+			path := filepath.Join(t.TempDir(), "file.txt")
+			if c.temp != nil {
+				ioutil.WriteFile(path+downloadTempSuffix, c.temp, 0644)
+			}
+			if c.validator != "" {
+				ioutil.WriteFile(path+downloadValidatorSuffix, []byte(c.validator), 0644)
+			}
+
+			err := NewHttpClient().DownloadFile(server.URL, path, &DownloadOptions{Md5: c.md5, Resume: true})
+			if (err == nil) != c.valid {
+				t.Fatalf("DownloadFile() error = %v", err)
+			}
+
+			lock.Lock()
+			if len(headers) != len(c.ranges) {
+				t.Fatalf("请求了%d次, expected %d", len(headers), len(c.ranges))
+			}
+			for i, header := range headers {
+				if header.Get("Range") != c.ranges[i] {
+					t.Errorf("第%d次请求 Range = %q, expected %q", i+1, header.Get("Range"), c.ranges[i])
+				}
+				if header.Get("Range") != "" && header.Get("If-Range") != c.validator {
+					t.Errorf("If-Range = %q, expected %q", header.Get("If-Range"), c.validator)
+				}
+			}
+			lock.Unlock()
+
+			data, err := ioutil.ReadFile(path)
+			if c.valid && !bytes.Equal(data, content) {
+				t.Errorf("文件内容不正确, 长度 %d", len(data))
+			}
+			if !c.valid && !os.IsNotExist(err) {
+				t.Errorf("校验失败时不应该生成文件")
+			}
+
+			// 完成或校验失败后不保留临时文件
+			for _, suffix := range []string{downloadTempSuffix, downloadValidatorSuffix} {
+				if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+					t.Errorf("%s 没有删除", suffix)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package commonlib
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// 响应体解码方法，根据Content-Encoding包装原始响应体
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	contentDecoderLock  sync.RWMutex
+	contentDecoders     = map[string]ContentDecoder{}
+	contentDecoderNames []string // 注册顺序，用于生成Accept-Encoding
+)
+
+func init() {
+	RegisterContentDecoder("gzip", func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+	RegisterContentDecoder("deflate", newDeflateReader)
+}
+
+/**
+ * 注册响应体解码方式
+ * @param encoding	Content-Encoding的取值，如 br、zstd
+ * @param decoder	解码方法
+ *
+ * 注册后该编码会出现在请求头Accept-Encoding中，只有已注册的编码才会向服务端声明
+ */
+func RegisterContentDecoder(encoding string, decoder ContentDecoder) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+
+	contentDecoderLock.Lock()
+	defer contentDecoderLock.Unlock()
+
+	if _, ok := contentDecoders[encoding]; !ok {
+		contentDecoderNames = append(contentDecoderNames, encoding)
+	}
+	contentDecoders[encoding] = decoder
+}
+
+// 生成请求头Accept-Encoding，只包含已注册的解码方式
+func AcceptEncoding() string {
+	contentDecoderLock.RLock()
+	defer contentDecoderLock.RUnlock()
+
+	return strings.Join(contentDecoderNames, ", ")
+}
+
+// 根据Content-Encoding解码响应体，多个编码时按相反顺序逐层解码
+func decodeContent(body io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	closers := multiCloser{}
+	reader := body
+
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+
+		contentDecoderLock.RLock()
+		decoder, ok := contentDecoders[encoding]
+		contentDecoderLock.RUnlock()
+
+		if !ok {
+			closers.Close()
+			return nil, errors.New("不支持的Content-Encoding: " + encoding)
+		}
+
+		rc, err := decoder(reader)
+		if err != nil {
+			closers.Close()
+			return nil, err
+		}
+		closers = append(closers, rc)
+		reader = rc
+	}
+
+	return &decodedBody{Reader: reader, closers: closers}, nil
+}
+
+// deflate按规范应为zlib格式，但部分服务端直接返回裸deflate数据，这里根据头部自动识别
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+// gzip压缩请求体
+func gzipBytes(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var err error
+	for i := len(mc) - 1; i >= 0; i-- {
+		if cErr := mc[i].Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+type decodedBody struct {
+	io.Reader
+	closers multiCloser
+}
+
+func (d *decodedBody) Close() error {
+	return d.closers.Close()
+}
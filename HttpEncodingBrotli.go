@@ -0,0 +1,17 @@
+//go:build brotli
+// +build brotli
+
+package commonlib
+
+import (
+	"github.com/andybalholm/brotli"
+	"io"
+	"io/ioutil"
+)
+
+// 使用 -tags brotli 编译时启用br解码
+func init() {
+	RegisterContentDecoder("br", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	})
+}
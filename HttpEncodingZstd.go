@@ -0,0 +1,20 @@
+//go:build zstd
+// +build zstd
+
+package commonlib
+
+import (
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+// 使用 -tags zstd 编译时启用zstd解码
+func init() {
+	RegisterContentDecoder("zstd", func(r io.Reader) (io.ReadCloser, error) {
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	})
+}
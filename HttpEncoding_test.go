@@ -0,0 +1,108 @@
+package commonlib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 按encodings的顺序逐层编码，与Content-Encoding中的顺序一致
+func encodeContent(t *testing.T, data []byte, encodings ...string) []byte {
+	for _, encoding := range encodings {
+		buf := &bytes.Buffer{}
+		var writer io.WriteCloser
+		switch encoding {
+		case "gzip":
+			writer = gzip.NewWriter(buf)
+		case "zlib":
+			writer = zlib.NewWriter(buf)
+		case "raw-deflate":
+			writer, _ = flate.NewWriter(buf, flate.DefaultCompression)
+		case "base64":
+			writer = base64.NewEncoder(base64.StdEncoding, buf)
+		default:
+			t.Fatalf("未知编码: %s", encoding)
+		}
+		writer.Write(data)
+		writer.Close()
+		data = buf.Bytes()
+	}
+	return data
+}
+
+func TestDecodeContent(t *testing.T) {
+	RegisterContentDecoder("X-Base64", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+	})
+
+	text := []byte(strings.Repeat("测试内容 content ", 100))
+
+	cases := []struct {
+		name            string
+		contentEncoding string
+		encodings       []string
+		ok              bool
+	}{
+		{"不压缩", "", nil, true},
+		{"identity", "identity", nil, true},
+		{"gzip", "gzip", []string{"gzip"}, true},
+		{"zlib格式的deflate", "deflate", []string{"zlib"}, true},
+		{"裸deflate", "deflate", []string{"raw-deflate"}, true},
+		{"大小写及空格", " GZip ", []string{"gzip"}, true},
+		{"多层编码", "gzip, deflate", []string{"gzip", "zlib"}, true},
+		{"多层编码逆序", "deflate, gzip", []string{"raw-deflate", "gzip"}, true},
+		{"自定义解码", "gzip, x-base64", []string{"gzip", "base64"}, true},
+		{"包含identity", "gzip, identity", []string{"gzip"}, true},
+		{"不支持的编码", "gzip, compress", []string{"gzip"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reader, err := decodeContent(bytes.NewReader(encodeContent(t, text, c.encodings...)), c.contentEncoding)
+			if !c.ok {
+				if err == nil {
+					t.Error("应该返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			data, err := ioutil.ReadAll(reader)
+			if err != nil || !bytes.Equal(data, text) {
+				t.Errorf("decodeContent() = %d bytes, error = %v", len(data), err)
+			}
+		})
+	}
+
+	if accept := AcceptEncoding(); !strings.HasPrefix(accept, "gzip, deflate") || !strings.Contains(accept, "x-base64") {
+		t.Errorf("AcceptEncoding() = %s", accept)
+	}
+}
+
+func TestHttpClientDecodeContent(t *testing.T) {
+	text := "多层压缩的响应"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "deflate") {
+			t.Errorf("Accept-Encoding = %s", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip, deflate")
+		w.Write(encodeContent(t, []byte(text), "gzip", "raw-deflate"))
+	}))
+	defer server.Close()
+
+	body, err := NewHttpClient().Get(server.URL)
+	if err != nil || string(body) != text {
+		t.Errorf("Get() = %s, error = %v", body, err)
+	}
+}
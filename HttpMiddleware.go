@@ -0,0 +1,121 @@
+package commonlib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// 将函数转换为http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+/**
+ * http客户端中间件，包装下一个RoundTripper，用于统一添加鉴权、签名、日志等处理
+ *
+ * example:
+ * client.Use(func(next http.RoundTripper) http.RoundTripper {
+ *   return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+ *     req = req.Clone(req.Context())
+ *     req.Header.Set("X-Token", token)
+ *     return next.RoundTrip(req)
+ *   })
+ * })
+ */
+type HttpMiddleware func(next http.RoundTripper) http.RoundTripper
+
+/**
+ * 添加中间件
+ * 先添加的中间件在外层，即请求时先执行，响应时后执行
+ */
+func (c *HttpClient) Use(middlewares ...HttpMiddleware) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// 获取包装了中间件的http.Client
+func (c *HttpClient) httpClient() *http.Client {
+	c.lock.RLock()
+	middlewares := c.middlewares
+	c.lock.RUnlock()
+
+	if len(middlewares) == 0 {
+		return c.Client
+	}
+
+	transport := c.Client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+
+	client := *c.Client
+	client.Transport = transport
+
+	return &client
+}
+
+// 设置固定请求头的中间件
+func HeaderMiddleware(key, value string) HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			request = request.Clone(request.Context())
+			request.Header.Set(key, value)
+			return next.RoundTrip(request)
+		})
+	}
+}
+
+/**
+ * 为没有请求ID的请求设置请求ID，header为请求头名称，如 X-Request-Id
+ * 请求context中有请求ID(见WithRequestId)时使用该ID，用于在服务之间传递，否则生成随机请求ID
+ */
+func RequestIdMiddleware(header string) HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Header.Get(header) == "" {
+				requestId := RequestIdFrom(request.Context())
+				if requestId == "" {
+					requestId = NewRequestId()
+				}
+				request = request.Clone(request.Context())
+				request.Header.Set(header, requestId)
+			}
+			return next.RoundTrip(request)
+		})
+	}
+}
+
+// 记录请求方法、地址、状态码及耗时的中间件，日志中包含请求context中的字段
+func LoggingMiddleware() HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			log := LogFrom(request.Context())
+			t := time.Now()
+			resp, err := next.RoundTrip(request)
+			if err != nil {
+				log.Error("Http ", request.Method, " :", request.URL.String(), "发生错误:", err, ",耗时:", time.Now().Sub(t))
+				return resp, err
+			}
+			log.Debug("Http ", request.Method, " :", request.URL.String(), ",状态:", resp.StatusCode, ",耗时:", time.Now().Sub(t))
+			return resp, err
+		})
+	}
+}
+
+// 生成32位随机请求ID
+func NewRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return GetMd5String(time.Now().String())
+	}
+	return hex.EncodeToString(b)
+}
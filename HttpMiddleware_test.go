@@ -0,0 +1,99 @@
+package commonlib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHttpMiddlewareOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Name")))
+	}))
+	defer server.Close()
+
+	var lock sync.Mutex
+	var steps []string
+	step := func(name string) HttpMiddleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+				lock.Lock()
+				steps = append(steps, name+">")
+				lock.Unlock()
+
+				resp, err := next.RoundTrip(request)
+
+				lock.Lock()
+				steps = append(steps, "<"+name)
+				lock.Unlock()
+				return resp, err
+			})
+		}
+	}
+
+	client := NewHttpClient()
+	client.Use(step("a"), step("b"))
+	client.Use(step("c"))
+	// 内层的中间件后设置请求头，覆盖外层的取值
+	client.Use(HeaderMiddleware("X-Name", "outer"), HeaderMiddleware("X-Name", "inner"))
+
+	body, err := client.Get(server.URL)
+	if err != nil || string(body) != "inner" {
+		t.Errorf("Get() = %s, error = %v", body, err)
+	}
+
+	expected := "a> b> c> <c <b <a"
+	if order := strings.Join(steps, " "); order != expected {
+		t.Errorf("执行顺序 = %s, expected %s", order, expected)
+	}
+}
+
+func TestHttpMiddlewareShortCircuit(t *testing.T) {
+	client := NewHttpClient()
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("cached")),
+				Request:    request,
+			}, nil
+		})
+	})
+
+	// 外层直接返回响应时不发送真实请求
+	body, err := client.Get("http://127.0.0.1:1/unreachable")
+	if err != nil || string(body) != "cached" {
+		t.Errorf("Get() = %s, error = %v", body, err)
+	}
+}
+
+func TestRequestIdMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Request-Id")))
+	}))
+	defer server.Close()
+
+	client := NewHttpClient()
+	client.Use(RequestIdMiddleware("X-Request-Id"))
+
+	first, err := client.Get(server.URL)
+	if err != nil || len(first) == 0 {
+		t.Errorf("生成的请求ID = %s, error = %v", first, err)
+	}
+	body, err := client.Get(server.URL)
+	if err != nil || len(body) == 0 || string(body) == string(first) {
+		t.Errorf("每次请求应该生成新的请求ID = %s, error = %v", body, err)
+	}
+
+	// 外层已设置请求ID时不覆盖
+	client = NewHttpClient()
+	client.Use(HeaderMiddleware("X-Request-Id", "fixed"), RequestIdMiddleware("X-Request-Id"))
+	body, err = client.Get(server.URL)
+	if err != nil || string(body) != "fixed" {
+		t.Errorf("已有的请求ID = %s, error = %v", body, err)
+	}
+}
@@ -0,0 +1,279 @@
+package commonlib
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 传输进度回调，sent为已传输字节数，total为总字节数(无法确定时为-1)
+type ProgressFunc func(sent, total int64)
+
+// multipart表单，文件内容在发送时才读取并以流的方式写入请求体
+type MultipartForm struct {
+	parts []*multipartPart
+
+	// 上传进度回调，可以为nil
+	Progress ProgressFunc
+}
+
+type multipartPart struct {
+	fieldName   string
+	fileName    string
+	contentType string
+	value       string
+	path        string
+	reader      io.Reader
+	size        int64
+	isFile      bool
+}
+
+func NewMultipartForm() *MultipartForm {
+	return new(MultipartForm)
+}
+
+// 添加普通字段
+func (f *MultipartForm) AddField(name, value string) {
+	f.parts = append(f.parts, &multipartPart{
+		fieldName: name,
+		value:     value,
+		size:      int64(len(value)),
+	})
+}
+
+/**
+ * 添加本地文件
+ * @param fieldName	表单字段名
+ * @param path		文件路径
+ * @param contentType	文件类型，为空时根据扩展名判断
+ *
+ * return 错误信息(文件不存在等)
+ */
+func (f *MultipartForm) AddFile(fieldName, path, contentType string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+
+	f.parts = append(f.parts, &multipartPart{
+		fieldName:   fieldName,
+		fileName:    filepath.Base(path),
+		contentType: contentType,
+		path:        path,
+		size:        info.Size(),
+		isFile:      true,
+	})
+
+	return nil
+}
+
+/**
+ * 添加文件内容
+ * @param fieldName	表单字段名
+ * @param fileName	文件名
+ * @param contentType	文件类型，为空时根据扩展名判断
+ * @param reader	文件内容，实现了io.Closer时发送后自动关闭
+ */
+func (f *MultipartForm) AddReader(fieldName, fileName, contentType string, reader io.Reader) {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fileName))
+	}
+
+	f.parts = append(f.parts, &multipartPart{
+		fieldName:   fieldName,
+		fileName:    fileName,
+		contentType: contentType,
+		reader:      reader,
+		size:        readerSize(reader),
+		isFile:      true,
+	})
+}
+
+// 计算请求体总长度，存在长度未知的内容时返回-1
+func (f *MultipartForm) contentLength(boundary string) int64 {
+	var total int64
+
+	for _, part := range f.parts {
+		if part.size < 0 {
+			return -1
+		}
+		total += part.size
+	}
+
+	// 按相同的boundary生成不含内容的表单，得到分隔符和头部的长度
+	counter := &countWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return -1
+	}
+	for _, part := range f.parts {
+		if _, err := part.create(writer); err != nil {
+			return -1
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return -1
+	}
+
+	return total + counter.n
+}
+
+func (f *MultipartForm) writeTo(writer *multipart.Writer) error {
+	for _, part := range f.parts {
+		if err := part.writeTo(writer); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func (p *multipartPart) create(writer *multipart.Writer) (io.Writer, error) {
+	if !p.isFile {
+		return writer.CreateFormField(p.fieldName)
+	}
+
+	contentType := p.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="`+escapeQuotes(p.fieldName)+`"; filename="`+escapeQuotes(p.fileName)+`"`)
+	header.Set("Content-Type", contentType)
+
+	return writer.CreatePart(header)
+}
+
+func (p *multipartPart) writeTo(writer *multipart.Writer) error {
+	w, err := p.create(writer)
+	if err != nil {
+		return err
+	}
+
+	if !p.isFile {
+		_, err = io.WriteString(w, p.value)
+		return err
+	}
+
+	reader := p.reader
+	if p.path != "" {
+		file, err := os.Open(p.path)
+		if err != nil {
+			return err
+		}
+		reader = file
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+/**
+ * 以multipart/form-data方式发送表单，请求体通过io.Pipe流式写入，不会将文件整体读入内存
+ * @param url	请求地址
+ * @param form	表单内容
+ *
+ * return 响应内容， 错误信息
+ */
+func (c *HttpClient) PostMultipart(url string, form *MultipartForm) ([]byte, error) {
+	return c.PostMultipartContext(context.Background(), url, form)
+}
+
+// 带context的multipart请求，ctx用于超时、取消及日志字段，见PostMultipart
+func (c *HttpClient) PostMultipartContext(ctx context.Context, url string, form *MultipartForm) ([]byte, error) {
+
+	log := LogFrom(ctx)
+	log.Trace("Http POST File :" + url)
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	total := form.contentLength(writer.Boundary())
+
+	go func() {
+		pw.CloseWithError(form.writeTo(writer))
+	}()
+
+	body := &progressReader{ReadCloser: pr, total: total, progress: form.Progress}
+
+	request, err := c.newRequest("POST", url, body)
+	if err != nil {
+		pr.Close()
+		log.Error("Http POST File :", url, "发生错误:", err)
+		return nil, err
+	}
+	request.ContentLength = total
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return c.do("Http POST File :", request.WithContext(ctx))
+}
+
+func HttpPostMultipart(url string, form *MultipartForm) ([]byte, error) {
+	return DefaultHttpClient.PostMultipart(url, form)
+}
+
+// 获取reader的内容长度，无法确定时返回-1
+func readerSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+
+	return -1
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// 读取时回调进度的reader
+type progressReader struct {
+	io.ReadCloser
+	sent     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		if r.progress != nil {
+			r.progress(r.sent, r.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,127 @@
+package commonlib
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 返回收到的表单字段及文件内容
+func multipartEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result := make(map[string]string)
+		for key := range r.MultipartForm.Value {
+			result[key] = r.FormValue(key)
+		}
+		for key, files := range r.MultipartForm.File {
+			for _, header := range files {
+				file, _ := header.Open()
+				data, _ := ioutil.ReadAll(file)
+				file.Close()
+				result[key+":"+header.Filename] = string(data)
+			}
+		}
+		result["contentLength"] = strings.TrimSpace(r.Header.Get("Content-Length"))
+
+		json.NewEncoder(w).Encode(result)
+	}))
+}
+
+func TestPostMultipart(t *testing.T) {
+	server := multipartEchoServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	pathA := filepath.Join(dir, "a.txt")
+	pathB := filepath.Join(dir, "b.txt")
+	ioutil.WriteFile(pathA, []byte(strings.Repeat("a", 100000)), 0644)
+	ioutil.WriteFile(pathB, []byte("bbb"), 0644)
+
+	form := NewMultipartForm()
+	form.AddField("name", "测试")
+	form.AddField("type", "1")
+	if err := form.AddFile("files", pathA, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.AddFile("files", pathB, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	form.AddReader("extra", "c.txt", "", strings.NewReader("ccc"))
+
+	var calls int
+	var lastSent, lastTotal int64
+	form.Progress = func(sent, total int64) {
+		if sent < lastSent {
+			t.Errorf("进度回退: %d < %d", sent, lastSent)
+		}
+		calls++
+		lastSent, lastTotal = sent, total
+	}
+
+	data, err := NewHttpClient().PostMultipart(server.URL, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[string]string)
+	if err = json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err, string(data))
+	}
+
+	expected := map[string]string{
+		"name":        "测试",
+		"type":        "1",
+		"files:a.txt": strings.Repeat("a", 100000),
+		"files:b.txt": "bbb",
+		"extra:c.txt": "ccc",
+	}
+	for key, val := range expected {
+		if result[key] != val {
+			t.Errorf("%s = %.20q, expected %.20q", key, result[key], val)
+		}
+	}
+
+	if calls == 0 || lastSent != lastTotal || lastTotal <= 100000 {
+		t.Errorf("进度回调 calls %d, sent %d, total %d", calls, lastSent, lastTotal)
+	}
+	if result["contentLength"] != "" && result["contentLength"] != strconv.FormatInt(lastTotal, 10) {
+		t.Errorf("Content-Length = %s, expected %d", result["contentLength"], lastTotal)
+	}
+}
+
+func TestPostMultipartOpenError(t *testing.T) {
+	server := multipartEchoServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	ioutil.WriteFile(path, []byte("aaa"), 0644)
+
+	form := NewMultipartForm()
+	form.AddField("name", "a")
+	if err := form.AddFile("file", path, ""); err != nil {
+		t.Fatal(err)
+	}
+	// 添加后文件被删除，发送时打开失败
+	os.Remove(path)
+
+	_, err := NewHttpClient().PostMultipart(server.URL, form)
+	if err == nil {
+		t.Fatal("PostMultipart() 应该返回错误")
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("PostMultipart() error = %v, expected 文件不存在的错误", err)
+	}
+}
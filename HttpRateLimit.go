@@ -0,0 +1,268 @@
+package commonlib
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 令牌桶限流
+type TokenBucket struct {
+	rate  float64 // 每秒产生的令牌数
+	burst float64 // 桶容量
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+/**
+ * 创建令牌桶
+ * @param rate	每秒允许的请求数，小于等于0时不限制
+ * @param burst	允许的突发请求数，小于1时为1
+ */
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// 等待获取一个令牌，ctx取消时返回ctx.Err()
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	// rate为0或NaN时等待时间无法计算，视为不限制
+	if !(b.rate > 0) {
+		return nil
+	}
+
+	b.lock.Lock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// 先预占令牌，令牌不足时计算需要等待的时间
+	b.tokens--
+	if b.tokens >= 0 {
+		b.lock.Unlock()
+		return nil
+	}
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.lock.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.refund()
+		return ctx.Err()
+	}
+}
+
+// 归还预占的令牌
+func (b *TokenBucket) refund() {
+	if !(b.rate > 0) {
+		return
+	}
+
+	b.lock.Lock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+	b.lock.Unlock()
+}
+
+// 限流统计
+type RateLimitStats struct {
+	Requests int64         // 经过限流的请求数
+	Delayed  int64         // 被延迟的请求数
+	WaitTime time.Duration // 累计等待时间
+	MaxWait  time.Duration // 最长等待时间
+	Canceled int64         // 等待期间被取消的请求数
+}
+
+/**
+ * 按域名限流的中间件
+ *
+ * example:
+ *   limiter := NewRateLimiter(20)
+ *   limiter.SetLimit("www.example.com", 2, 1)
+ *   client.Use(limiter.Middleware())
+ */
+type RateLimiter struct {
+	lock     sync.Mutex
+	limits   map[string]*TokenBucket
+	fallback func() *TokenBucket
+	sem      chan struct{}
+	stats    map[string]*RateLimitStats
+}
+
+/**
+ * 创建限流器
+ * @param maxConcurrent	全局最大并发请求数，小于等于0时不限制
+ */
+func NewRateLimiter(maxConcurrent int) *RateLimiter {
+	l := &RateLimiter{
+		limits: make(map[string]*TokenBucket),
+		stats:  make(map[string]*RateLimitStats),
+	}
+	if maxConcurrent > 0 {
+		l.sem = make(chan struct{}, maxConcurrent)
+	}
+
+	return l
+}
+
+// 设置域名的限流，host为请求地址中的域名(可带端口)，rate小于等于0时不限制
+func (l *RateLimiter) SetLimit(host string, rate float64, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.limits[strings.ToLower(host)] = NewTokenBucket(rate, burst)
+}
+
+// 设置未单独配置的域名的默认限流，每个域名使用独立的令牌桶
+func (l *RateLimiter) SetDefaultLimit(rate float64, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.fallback = func() *TokenBucket {
+		return NewTokenBucket(rate, burst)
+	}
+}
+
+// 获取各域名的限流统计
+func (l *RateLimiter) Stats() map[string]RateLimitStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	result := make(map[string]RateLimitStats, len(l.stats))
+	for host, stats := range l.stats {
+		result[host] = *stats
+	}
+
+	return result
+}
+
+func (l *RateLimiter) bucket(host string) *TokenBucket {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if b, ok := l.limits[host]; ok {
+		return b
+	}
+	if l.fallback == nil {
+		return nil
+	}
+
+	b := l.fallback()
+	l.limits[host] = b
+
+	return b
+}
+
+func (l *RateLimiter) record(host string, wait time.Duration, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats, ok := l.stats[host]
+	if !ok {
+		stats = &RateLimitStats{}
+		l.stats[host] = stats
+	}
+
+	stats.Requests++
+	if err != nil {
+		stats.Canceled++
+	}
+	if wait > time.Millisecond {
+		stats.Delayed++
+	}
+	stats.WaitTime += wait
+	if wait > stats.MaxWait {
+		stats.MaxWait = wait
+	}
+}
+
+// 等待限流，ctx取消时返回ctx.Err()，返回的release方法用于请求结束后释放并发数
+func (l *RateLimiter) Wait(ctx context.Context, host string) (release func(), err error) {
+	host = strings.ToLower(host)
+	t := time.Now()
+	release = func() {}
+
+	defer func() {
+		l.record(host, time.Now().Sub(t), err)
+	}()
+
+	b := l.bucket(host)
+	if b != nil {
+		if err = b.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+			release = func() { <-l.sem }
+		case <-ctx.Done():
+			// 请求没有发出，归还已获取的令牌
+			if b != nil {
+				b.refund()
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+func (l *RateLimiter) Middleware() HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			release, err := l.Wait(request.Context(), request.URL.Host)
+			if err != nil {
+				// RoundTripper出错时也需要关闭请求体，否则流式上传的写入方会一直阻塞
+				if request.Body != nil {
+					request.Body.Close()
+				}
+				LogFrom(request.Context()).Warn("Http ", request.Method, " :", request.URL.String(), "限流等待被取消:", err)
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(request)
+			if err != nil || resp.Body == nil {
+				release()
+				return resp, err
+			}
+
+			// 响应体读取完毕后才释放并发数
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+
+			return resp, nil
+		})
+	}
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,146 @@
+package commonlib
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		b := NewTokenBucket(rate, 1)
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			if err := b.Wait(context.Background()); err != nil {
+				t.Fatalf("rate %v: Wait() error = %v", rate, err)
+			}
+		}
+		if elapsed := time.Now().Sub(start); elapsed > 50*time.Millisecond {
+			t.Errorf("rate %v: Wait() 不应该等待, elapsed %v", rate, elapsed)
+		}
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	b := NewTokenBucket(20, 2)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 前2个使用突发容量，第3个需要等待约50ms
+	elapsed := time.Now().Sub(start)
+	if elapsed < 40*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("Wait() elapsed %v, expected about 50ms", elapsed)
+	}
+}
+
+func TestTokenBucketCancelRefund(t *testing.T) {
+	b := NewTokenBucket(1, 1)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait() error = %v, expected DeadlineExceeded", err)
+	}
+
+	// 取消的请求归还了令牌，不影响之后的等待时间
+	b.lock.Lock()
+	tokens := b.tokens
+	b.lock.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("tokens = %v, 取消时应该归还令牌", tokens)
+	}
+}
+
+func TestRateLimiterSemaphoreCancelRefund(t *testing.T) {
+	l := NewRateLimiter(1)
+	l.SetLimit("example.com", 1, 2)
+
+	release, err := l.Wait(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// 并发数已满，等待并发时被取消
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, "example.com"); err != context.DeadlineExceeded {
+		t.Fatalf("Wait() error = %v, expected DeadlineExceeded", err)
+	}
+
+	b := l.bucket("example.com")
+	b.lock.Lock()
+	tokens := b.tokens
+	b.lock.Unlock()
+	if tokens < 0.9 {
+		t.Errorf("tokens = %v, 等待并发被取消时应该归还令牌", tokens)
+	}
+
+	stats := l.Stats()["example.com"]
+	if stats.Requests != 2 || stats.Canceled != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+type closeRecorder struct {
+	io.ReadCloser
+	closed chan struct{}
+}
+
+func (r *closeRecorder) Close() error {
+	close(r.closed)
+	return r.ReadCloser.Close()
+}
+
+func TestRateLimiterMiddlewareClosesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	l := NewRateLimiter(1)
+	release, err := l.Wait(context.Background(), strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// 并发数已满，等待被取消
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	closed := make(chan struct{})
+	client := NewHttpClient()
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			request = request.WithContext(ctx)
+			request.Body = &closeRecorder{ReadCloser: request.Body, closed: closed}
+			return next.RoundTrip(request)
+		})
+	}, l.Middleware())
+
+	form := NewMultipartForm()
+	form.AddField("name", "a")
+	form.AddReader("file", "a.txt", "", strings.NewReader(strings.Repeat("a", 1<<20)))
+
+	if _, err := client.PostMultipart(server.URL, form); err == nil {
+		t.Fatal("PostMultipart() 应该返回错误")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("等待被取消时没有关闭请求体")
+	}
+}
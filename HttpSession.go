@@ -0,0 +1,237 @@
+package commonlib
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * 保存cookie的http会话，用于先登录再访问的多步请求
+ *
+ * example:
+ *   session, err := NewHttpSession("data/portal.cookies")
+ *   _, err = session.Post("http://portal/login", "user=a&pwd=b")
+ *   body, err := session.Get("http://portal/list")
+ *   err = session.Save()
+ */
+type HttpSession struct {
+	*HttpClient
+
+	Jar *PersistentJar
+
+	// 最大重定向次数，0使用默认值10，小于0时不跟随重定向
+	MaxRedirects int
+
+	// 只跟随同一域名下的重定向
+	SameHostRedirects bool
+
+	lock    sync.Mutex
+	referer string
+}
+
+/**
+ * 创建会话
+ * @param cookieFile	cookie保存路径，文件存在时加载其中的cookie，为空时只在内存中保存
+ */
+func NewHttpSession(cookieFile string) (*HttpSession, error) {
+	jar, err := NewPersistentJar(cookieFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &HttpSession{
+		HttpClient: NewHttpClient(),
+		Jar:        jar,
+	}
+	s.Client.Jar = jar
+	s.Client.CheckRedirect = s.checkRedirect
+	s.Use(s.refererMiddleware())
+
+	return s, nil
+}
+
+// 保存cookie到文件
+func (s *HttpSession) Save() error {
+	return s.Jar.Save()
+}
+
+// 当前的Referer，即上一次请求的页面地址
+func (s *HttpSession) Referer() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.referer
+}
+
+// 设置下一次请求使用的Referer
+func (s *HttpSession) SetReferer(referer string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.referer = referer
+}
+
+func (s *HttpSession) checkRedirect(request *http.Request, via []*http.Request) error {
+	if s.MaxRedirects < 0 {
+		return http.ErrUseLastResponse
+	}
+
+	max := s.MaxRedirects
+	if max == 0 {
+		max = 10
+	}
+	if len(via) >= max {
+		return errors.New("重定向次数超过" + strconv.Itoa(max) + "次")
+	}
+
+	if s.SameHostRedirects && request.URL.Host != via[0].URL.Host {
+		return http.ErrUseLastResponse
+	}
+
+	return nil
+}
+
+// 使用上一次请求的页面作为Referer，重定向时由http.Client设置Referer
+func (s *HttpSession) refererMiddleware() HttpMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Response == nil {
+				request = request.Clone(request.Context())
+				if referer := s.Referer(); referer != "" {
+					request.Header.Set("Referer", referer)
+				} else {
+					request.Header.Del("Referer")
+				}
+			}
+
+			resp, err := next.RoundTrip(request)
+			if err == nil && (resp.StatusCode < 300 || resp.StatusCode >= 400) {
+				s.SetReferer(request.URL.String())
+			}
+
+			return resp, err
+		})
+	}
+}
+
+// 可以保存到文件的cookie jar
+type PersistentJar struct {
+	*cookiejar.Jar
+
+	Path string
+
+	lock    sync.Mutex
+	entries map[string]*jarEntry
+}
+
+type jarEntry struct {
+	Url    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+/**
+ * 创建cookie jar
+ * @param path	保存路径，文件存在时加载其中的cookie，为空时只在内存中保存
+ */
+func NewPersistentJar(path string) (*PersistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &PersistentJar{Jar: jar, Path: path, entries: make(map[string]*jarEntry)}
+
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*jarEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.New("cookie文件格式错误: " + err.Error())
+	}
+
+	for _, entry := range entries {
+		u, err := url.Parse(entry.Url)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{entry.Cookie})
+	}
+
+	return j, nil
+}
+
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	now := time.Now()
+	for _, cookie := range cookies {
+		c := *cookie
+		if c.MaxAge > 0 {
+			// MaxAge是相对时间，保存时转换为过期时间
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+		j.entries[jarEntryKey(u, &c)] = &jarEntry{Url: u.Scheme + "://" + u.Host + u.Path, Cookie: &c}
+	}
+}
+
+// 保存的key，设置了Domain的cookie在各子域名间共享，只按Domain区分，没有Domain时按请求的域名区分
+func jarEntryKey(u *url.URL, c *http.Cookie) string {
+	if c.Domain != "" {
+		return "domain;" + strings.ToLower(strings.TrimPrefix(c.Domain, ".")) + ";" + c.Path + ";" + c.Name
+	}
+	return "host;" + strings.ToLower(u.Hostname()) + ";" + c.Path + ";" + c.Name
+}
+
+// 保存未过期的cookie(包括会话cookie)，写入临时文件后重命名
+func (j *PersistentJar) Save() error {
+	if j.Path == "" {
+		return nil
+	}
+
+	j.lock.Lock()
+	now := time.Now()
+	entries := make([]*jarEntry, 0, len(j.entries))
+	for key, entry := range j.entries {
+		cookie := entry.Cookie
+		if cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(now)) {
+			delete(j.entries, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	j.lock.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempPath := j.Path + ".tmp"
+	if err = ioutil.WriteFile(tempPath, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, j.Path)
+}
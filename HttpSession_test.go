@@ -0,0 +1,102 @@
+package commonlib
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func cookieNames(jar http.CookieJar, rawUrl string) map[string]string {
+	u, _ := url.Parse(rawUrl)
+	names := make(map[string]string)
+	for _, c := range jar.Cookies(u) {
+		names[c.Name] = c.Value
+	}
+	return names
+}
+
+func TestPersistentJarSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar, err := NewPersistentJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("http://a.example.com/login")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "s1", Path: "/"},
+		{Name: "token", Value: "t1", Path: "/", Domain: "example.com", MaxAge: 3600},
+	})
+	if err = jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewPersistentJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		url      string
+		expected map[string]string
+	}{
+		{"http://a.example.com/", map[string]string{"session": "s1", "token": "t1"}},
+		// 没有Domain的cookie只发送给原域名
+		{"http://b.example.com/", map[string]string{"token": "t1"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			names := cookieNames(loaded, c.url)
+			if len(names) != len(c.expected) {
+				t.Fatalf("Cookies() = %v, expected %v", names, c.expected)
+			}
+			for name, value := range c.expected {
+				if names[name] != value {
+					t.Errorf("Cookies() = %v, expected %v", names, c.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestPersistentJarDomainCookie(t *testing.T) {
+	cases := []struct {
+		name     string
+		update   *http.Cookie
+		expected string // 重新加载后token的值，为空时期望已删除
+	}{
+		{"在其他子域名更新", &http.Cookie{Name: "token", Value: "t2", Path: "/", Domain: ".example.com"}, "t2"},
+		{"在其他子域名删除", &http.Cookie{Name: "token", Value: "", Path: "/", Domain: "example.com", MaxAge: -1}, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cookies.json")
+			jar, err := NewPersistentJar(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, _ := url.Parse("http://a.example.com/")
+			b, _ := url.Parse("http://b.example.com/")
+			jar.SetCookies(a, []*http.Cookie{{Name: "token", Value: "t1", Path: "/", Domain: "example.com"}})
+			jar.SetCookies(b, []*http.Cookie{c.update})
+			if err = jar.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			// 多次加载结果一致，不会因为保存了多条记录恢复旧的cookie
+			for i := 0; i < 5; i++ {
+				loaded, err := NewPersistentJar(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value := cookieNames(loaded, "http://a.example.com/")["token"]; value != c.expected {
+					t.Fatalf("token = %q, expected %q", value, c.expected)
+				}
+			}
+		})
+	}
+}
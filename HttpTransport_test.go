@@ -0,0 +1,158 @@
+package commonlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 创建测试证书，parent为nil时为自签名证书
+func newTestCert(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func TestPinnedKeysVerifiedChain(t *testing.T) {
+	root, rootKey := newTestCert(t, "root", true, nil, nil)
+	leaf, _ := newTestCert(t, "api.example.com", false, root, rootKey)
+	pinnedCA, _ := newTestCert(t, "pinned-ca", true, nil, nil)
+
+	tlsConfig, err := newTLSConfig(&HttpTransportConfig{PinnedKeys: []string{CertificateKeyPin(pinnedCA)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		rawCerts [][]byte
+		chains   [][]*x509.Certificate
+		valid    bool
+	}{
+		{
+			name:     "证书链中有固定的公钥",
+			rawCerts: [][]byte{leaf.Raw},
+			chains:   [][]*x509.Certificate{{leaf, pinnedCA}},
+			valid:    true,
+		},
+		{
+			name:     "服务端附加了固定的CA证书",
+			rawCerts: [][]byte{leaf.Raw, pinnedCA.Raw},
+			chains:   [][]*x509.Certificate{{leaf, root}},
+			valid:    false,
+		},
+		{
+			name:     "没有校验通过的证书链",
+			rawCerts: [][]byte{pinnedCA.Raw},
+			valid:    false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := tlsConfig.VerifyPeerCertificate(c.rawCerts, c.chains)
+			if (err == nil) != c.valid {
+				t.Errorf("VerifyPeerCertificate() error = %v, valid %v", err, c.valid)
+			}
+		})
+	}
+}
+
+func TestPinnedKeysInsecureSkipVerify(t *testing.T) {
+	selfSigned, _ := newTestCert(t, "api.example.com", false, nil, nil)
+	pinnedCA, _ := newTestCert(t, "pinned-ca", true, nil, nil)
+	other, _ := newTestCert(t, "attacker", false, nil, nil)
+
+	tlsConfig, err := newTLSConfig(&HttpTransportConfig{
+		InsecureSkipVerify: true,
+		PinnedKeys:         []string{CertificateKeyPin(selfSigned), CertificateKeyPin(pinnedCA)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tlsConfig.VerifyPeerCertificate([][]byte{selfSigned.Raw}, nil); err != nil {
+		t.Errorf("服务端证书公钥匹配 error = %v", err)
+	}
+
+	// 不校验证书链时，只有服务端证书本身可以匹配
+	if err := tlsConfig.VerifyPeerCertificate([][]byte{other.Raw, pinnedCA.Raw}, nil); err == nil {
+		t.Error("服务端附加了固定的CA证书，应该校验失败")
+	}
+}
+
+func TestPinnedKeysServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := newTestCert(t, "other", true, nil, nil)
+
+	cases := []struct {
+		name  string
+		pin   string
+		valid bool
+	}{
+		{"公钥匹配", CertificateKeyPin(server.Certificate()), true},
+		{"公钥不匹配", CertificateKeyPin(other), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			transport, err := NewHttpTransport(&HttpTransportConfig{CAFiles: []string{caFile}, PinnedKeys: []string{c.pin}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err == nil) != c.valid {
+				t.Errorf("Get() error = %v, valid %v", err, c.valid)
+			}
+		})
+	}
+}
@@ -2,200 +2,228 @@ package commonlib
 
 import (
 	"bytes"
-	"compress/gzip"
+	"context"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sync"
 )
 
-func HttpGet(url string) ([]byte, error) {
+// 公用的http请求客户端，HttpGet/HttpPost/Wululu*等函数均通过DefaultHttpClient发送请求
+type HttpClient struct {
+	Client *http.Client
 
-	Log.Trace("Http Get:" + url)
+	// 请求体长度达到该字节数时使用gzip压缩后发送，0表示不压缩
+	CompressMinSize int
 
-	client := &http.Client{
-		CheckRedirect: nil,
-	}
+	// 是否将响应体转换为UTF-8，字符集从Content-Type或html meta标签中识别
+	ConvertCharset bool
 
-	reqest, _ := http.NewRequest("GET", url, nil)
+	// 强制指定响应体字符集(如 gbk)，不为空时忽略识别结果并转换为UTF-8
+	Charset string
 
-	reqest.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
-	reqest.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	reqest.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	reqest.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
-	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	reqest.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
-	reqest.Header.Set("Cache-Control", "max-age=0")
-	reqest.Header.Set("Connection", "keep-alive")
-	reqest.Header.Set("Referer", url)
+	lock        sync.RWMutex
+	middlewares []HttpMiddleware
+}
 
-	resp, err := client.Do(reqest)
+var DefaultHttpClient = NewHttpClient()
 
-	if err != nil {
-		Log.Error("Http Get:", url, "发生错误:", err)
-		return nil, err
+func NewHttpClient() *HttpClient {
+	return &HttpClient{
+		Client: &http.Client{
+			CheckRedirect: nil,
+		},
 	}
+}
 
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("Http Get:", url, "发生错误:", err)
-			return nil, err
+/**
+ * 创建请求并设置公用的请求头
+ * @param method	请求方法
+ * @param url		请求地址
+ * @param body		请求体，可以为nil
+ *
+ * return 请求对象， 错误信息
+ */
+func (c *HttpClient) NewRequest(method, url string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	compressed := false
+
+	if body != nil {
+		if c.CompressMinSize > 0 && len(body) >= c.CompressMinSize {
+			gzBody, err := gzipBytes(body)
+			if err != nil {
+				return nil, err
+			}
+			body = gzBody
+			compressed = true
 		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
+		reader = bytes.NewReader(body)
 	}
 
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("Http Get:", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
+	request, err := c.newRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if compressed {
+		request.Header.Set("Content-Encoding", "gzip")
 	}
 
-	return nil, nil
+	return request, nil
 }
 
-func HttpPost(url, postStr string) ([]byte, error) {
+func (c *HttpClient) Get(url string) ([]byte, error) {
+	return c.GetContext(context.Background(), url)
+}
 
-	Log.Trace("Http POST :" + url + ",body:" + postStr)
+func (c *HttpClient) Post(url, postStr string) ([]byte, error) {
+	return c.PostContext(context.Background(), url, postStr)
+}
 
-	client := &http.Client{
-		CheckRedirect: nil,
-	}
+// 带context的GET请求，ctx用于超时、取消及日志字段(见WithLogFields)
+func (c *HttpClient) GetContext(ctx context.Context, url string) ([]byte, error) {
+	LogFrom(ctx).Trace("Http Get:" + url)
+	return c.sendContext(ctx, "Http Get:", "GET", url, nil)
+}
 
-	postBytesReader := bytes.NewReader([]byte(postStr))
-	reqest, _ := http.NewRequest("POST", url, postBytesReader)
+// 带context的POST请求，见GetContext
+func (c *HttpClient) PostContext(ctx context.Context, url, postStr string) ([]byte, error) {
+	LogFrom(ctx).Trace("Http POST :" + url + ",body:" + postStr)
+	return c.sendContext(ctx, "Http POST :", "POST", url, []byte(postStr))
+}
 
-	reqest.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
-	reqest.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	reqest.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	reqest.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
-	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	reqest.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
-	reqest.Header.Set("Cache-Control", "max-age=0")
-	reqest.Header.Set("Connection", "keep-alive")
-	reqest.Header.Set("Referer", url)
+/**
+ * 上传单个文件
+ * @param url		请求地址
+ * @param params	其他表单字段
+ * @param paramName	文件的表单字段名
+ * @param path		文件路径
+ *
+ * 多个文件或需要上传进度时使用PostMultipart
+ */
+func (c *HttpClient) PostFile(url string, params map[string]string, paramName, path string) ([]byte, error) {
+	return c.PostFileContext(context.Background(), url, params, paramName, path)
+}
 
-	resp, err := client.Do(reqest)
+// 带context的文件上传，见PostFile
+func (c *HttpClient) PostFileContext(ctx context.Context, url string, params map[string]string, paramName, path string) ([]byte, error) {
 
-	if err != nil {
-		Log.Error("Http POST :", url, "发生错误:", err)
+	form := NewMultipartForm()
+
+	if err := form.AddFile(paramName, path, ""); err != nil {
+		LogFrom(ctx).Error("Http POST File :", url, "发生错误:", err)
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("Http POST :", url, "发生错误:", err)
-			return nil, err
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
+	for key, val := range params {
+		form.AddField(key, val)
 	}
 
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("Http POST :", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
+	return c.PostMultipartContext(ctx, url, form)
+}
+
+func (c *HttpClient) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	setDefaultHeaders(request, url)
+
+	return request, nil
 }
 
-func HttpPostFile(url string, params map[string]string, paramName, path string) ([]byte, error) {
+/**
+ * 发送由NewRequest创建的请求，可以通过request.WithContext控制超时和取消
+ *
+ * return 解码后的响应体， 错误信息
+ */
+func (c *HttpClient) Do(request *http.Request) ([]byte, error) {
+	return c.do("Http "+request.Method+" :", request)
+}
 
-	client := &http.Client{
-		CheckRedirect: nil,
+// 发送请求并返回解码后的响应体
+func (c *HttpClient) sendContext(ctx context.Context, tag, method, url string, body []byte) ([]byte, error) {
+	request, err := c.NewRequest(method, url, body)
+	if err != nil {
+		LogFrom(ctx).Error(tag, url, "发生错误:", err)
+		return nil, err
 	}
 
-	file, err := os.Open(path)
+	return c.do(tag, request.WithContext(ctx))
+}
+
+func (c *HttpClient) do(tag string, request *http.Request) ([]byte, error) {
+	_, body, err := c.doResponse(tag, request)
+	return body, err
+}
+
+// 发送请求，返回响应(响应体已关闭)及解码后的响应体，日志中包含请求context中的字段
+func (c *HttpClient) doResponse(tag string, request *http.Request) (*http.Response, []byte, error) {
+	url := request.URL.String()
+	log := LogFrom(request.Context())
+
+	resp, err := c.httpClient().Do(request)
 
 	if err != nil {
-		return nil, err
+		log.Error(tag, url, "发生错误:", err)
+		return nil, nil, err
 	}
-	defer file.Close()
 
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(paramName, filepath.Base(path))
+	defer resp.Body.Close()
 
+	reader, err := decodeContent(resp.Body, resp.Header.Get("Content-Encoding"))
 	if err != nil {
-		Log.Error("Http POST File :", url, "发生错误:", err)
-		return nil, err
+		log.Error(tag, url, "发生错误:", err)
+		return resp, nil, err
 	}
-	_, err = io.Copy(part, file)
+	defer reader.Close()
 
-	for key, val := range params {
-		_ = writer.WriteField(key, val)
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		log.Error(tag, url, "发生错误:", err)
+		return resp, nil, err
 	}
 
-	err = writer.Close()
-
-	if err != nil {
-		Log.Error("Http POST File :", url, "发生错误:", err)
-		return nil, err
+	if c.ConvertCharset || c.Charset != "" {
+		body, err = ConvertToUTF8(body, resp.Header.Get("Content-Type"), c.Charset)
+		if err != nil {
+			log.Error(tag, url, "发生错误:", err)
+			return resp, nil, err
+		}
 	}
 
-	request, _ := http.NewRequest("POST", url, body)
+	return resp, body, nil
+}
 
+func setDefaultHeaders(request *http.Request, url string) {
 	request.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
 	request.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	request.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	request.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
+	request.Header.Set("Accept-Encoding", AcceptEncoding())
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
 	request.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
 	request.Header.Set("Cache-Control", "max-age=0")
 	request.Header.Set("Connection", "keep-alive")
 	request.Header.Set("Referer", url)
+}
 
-	resp, err := client.Do(request)
-
-	if err != nil {
-		Log.Error("Http POST File :", url, "发生错误:", err)
-		return nil, err
-	}
+func HttpGet(url string) ([]byte, error) {
+	return DefaultHttpClient.Get(url)
+}
 
-	defer resp.Body.Close()
+func HttpGetContext(ctx context.Context, url string) ([]byte, error) {
+	return DefaultHttpClient.GetContext(ctx, url)
+}
 
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("Http POST File :", url, "发生错误:", err)
-			return nil, err
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
-	}
+func HttpPost(url, postStr string) ([]byte, error) {
+	return DefaultHttpClient.Post(url, postStr)
+}
 
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("Http POST File :", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
-	}
+func HttpPostContext(ctx context.Context, url, postStr string) ([]byte, error) {
+	return DefaultHttpClient.PostContext(ctx, url, postStr)
+}
 
-	return nil, nil
+func HttpPostFile(url string, params map[string]string, paramName, path string) ([]byte, error) {
+	return DefaultHttpClient.PostFile(url, params, paramName, path)
 }
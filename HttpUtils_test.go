@@ -0,0 +1,116 @@
+package commonlib
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 返回请求方法、地址、请求体及请求ID
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + body.String() + " " + r.Header.Get("X-Request-Id")))
+	}))
+}
+
+func TestLegacyHttpDelegates(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	cases := []struct {
+		name     string
+		legacy   func() ([]byte, error)
+		context  func() ([]byte, error)
+		expected string
+	}{
+		{
+			"HttpGet",
+			func() ([]byte, error) { return HttpGet(server.URL + "/a?b=1") },
+			func() ([]byte, error) { return HttpGetContext(ctx, server.URL+"/a?b=1") },
+			"GET /a?b=1  ",
+		},
+		{
+			"HttpPost",
+			func() ([]byte, error) { return HttpPost(server.URL+"/a", "b=1") },
+			func() ([]byte, error) { return HttpPostContext(ctx, server.URL+"/a", "b=1") },
+			"POST /a b=1 ",
+		},
+		{
+			"WululuGet",
+			func() ([]byte, error) { return WululuGet(server.URL+"/a", "b=1") },
+			func() ([]byte, error) { return WululuGetContext(ctx, server.URL+"/a", "b=1") },
+			"GET /a?b=1  ",
+		},
+		{
+			"WululuPost",
+			func() ([]byte, error) { return WululuPost(server.URL+"/a", "b=1") },
+			func() ([]byte, error) { return WululuPostContext(ctx, server.URL+"/a", "b=1") },
+			"POST /a b=1 ",
+		},
+		{
+			"WululuPut",
+			func() ([]byte, error) { return WululuPut(server.URL+"/a", "b=1") },
+			func() ([]byte, error) { return WululuPutContext(ctx, server.URL+"/a", "b=1") },
+			"PUT /a b=1 ",
+		},
+		{
+			"WululuDelete",
+			func() ([]byte, error) { return WululuDelete(server.URL+"/a", "b=1") },
+			func() ([]byte, error) { return WululuDeleteContext(ctx, server.URL+"/a", "b=1") },
+			"DELETE /a b=1 ",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			legacy, err := c.legacy()
+			if err != nil || string(legacy) != c.expected {
+				t.Errorf("legacy = %q, %v, expected %q", legacy, err, c.expected)
+			}
+			withCtx, err := c.context()
+			if err != nil || string(withCtx) != c.expected {
+				t.Errorf("context = %q, %v, expected %q", withCtx, err, c.expected)
+			}
+		})
+	}
+}
+
+func TestHttpContext(t *testing.T) {
+	defer SetLogger(nil)
+
+	buf := &bytes.Buffer{}
+	SetLogger(NewWriterLogger(buf, LogFormatJSON))
+
+	server := echoServer()
+	defer server.Close()
+
+	ctx := WithRequestId(context.Background(), "req-1")
+
+	// 请求ID通过RequestIdMiddleware传递给接口
+	client := NewHttpClient()
+	client.Use(RequestIdMiddleware("X-Request-Id"))
+	body, err := client.GetContext(ctx, server.URL+"/a")
+	if err != nil || string(body) != "GET /a  req-1" {
+		t.Errorf("GetContext() = %q, %v", body, err)
+	}
+
+	// 日志中包含ctx中的字段
+	if _, err = WululuGetContext(ctx, server.URL+"/a", "b=1"); err != nil {
+		t.Fatal(err)
+	}
+	if output := buf.String(); !strings.Contains(output, `"requestId":"req-1"`) || !strings.Contains(output, "WululuGet") {
+		t.Errorf("日志内容 = %s", output)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = HttpGetContext(cancelled, server.URL); err == nil {
+		t.Error("ctx取消后应该返回错误")
+	}
+}
@@ -0,0 +1,277 @@
+package commonlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	localeLock sync.RWMutex
+
+	// 默认语言，内置的提示信息及未翻译的内容使用该语言
+	defaultLocale = "zh-CN"
+
+	locales = map[string]map[string]string{
+		"zh-CN": {
+			"db.begin":        "开启事务时，数据库异常： %v",
+			"db.commit":       "提交事务，数据库异常%v",
+			"db.close":        "关闭数据库连接，数据库异常%v",
+			"sign.missing":    "缺少签名参数",
+			"sign.invalid":    "签名错误",
+			"sign.expired":    "请求已过期",
+			"sign.timestamp":  "时间戳格式错误",
+			"sign.appKey":     "appKey无效",
+			"sign.replay":     "请求重复",
+			"sign.noAppKey":   "缺少appKey参数",
+			"sign.noNonce":    "缺少nonce参数",
+			"sign.noSecret":   "签名密钥为空",
+			"sign.duplicate":  "参数重复: %v",
+			"valid.type":      "类型错误，应为%v",
+			"valid.format":    "格式错误: %v",
+			"valid.required":  "不能为空",
+			"valid.min":       "不能小于%v",
+			"valid.max":       "不能大于%v",
+			"valid.minLength": "长度不能小于%v",
+			"valid.maxLength": "长度不能大于%v",
+			"valid.regexp":    "格式不正确",
+			"valid.enum":      "必须为%v之一",
+			"valid.email":     "邮箱格式不正确",
+			"valid.phone":     "手机号格式不正确",
+		},
+		"en-US": {
+			"code.0":          "Success",
+			"code.-1":         "Request failed",
+			"code.1001":       "Invalid parameters",
+			"code.2001":       "Database error",
+			"code.3001":       "Object not found",
+			"code.4001":       "Not logged in or session expired",
+			"code.4002":       "Signature verification failed",
+			"code.4003":       "Permission denied",
+			"db.begin":        "Database error when starting transaction: %v",
+			"db.commit":       "Database error when committing transaction: %v",
+			"db.close":        "Database error when closing connection: %v",
+			"sign.missing":    "Missing signature",
+			"sign.invalid":    "Invalid signature",
+			"sign.expired":    "Request expired",
+			"sign.timestamp":  "Invalid timestamp",
+			"sign.appKey":     "Invalid appKey",
+			"sign.replay":     "Duplicate request",
+			"sign.noAppKey":   "Missing appKey",
+			"sign.noNonce":    "Missing nonce",
+			"sign.noSecret":   "Signing secret is empty",
+			"sign.duplicate":  "Duplicate parameter: %v",
+			"valid.type":      "Invalid type, expected %v",
+			"valid.format":    "Invalid format: %v",
+			"valid.required":  "Required",
+			"valid.min":       "Must be at least %v",
+			"valid.max":       "Must be at most %v",
+			"valid.minLength": "Length must be at least %v",
+			"valid.maxLength": "Length must be at most %v",
+			"valid.regexp":    "Invalid format",
+			"valid.enum":      "Must be one of %v",
+			"valid.email":     "Invalid email address",
+			"valid.phone":     "Invalid phone number",
+		},
+	}
+)
+
+/**
+ * 加载语言文件
+ * @param locale	语言，如 zh-CN、en-US
+ * @param path		json文件路径，内容为 {"key": "文字", ...}
+ *
+ * 文件中的内容会覆盖已有的同名key
+ */
+func LoadLocaleFile(locale, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	messages := make(map[string]string)
+	if err = json.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("语言文件格式错误(%s): %v", path, err)
+	}
+
+	localeLock.Lock()
+	defer localeLock.Unlock()
+
+	catalog, ok := locales[locale]
+	if !ok {
+		catalog = make(map[string]string)
+		locales[locale] = catalog
+	}
+	for key, val := range messages {
+		catalog[key] = val
+	}
+
+	return nil
+}
+
+// 加载目录下的所有语言文件，文件名为语言名，如 conf/locale/en-US.json
+func LoadLocaleDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		locale := strings.TrimSuffix(filepath.Base(file), ".json")
+		if err = LoadLocaleFile(locale, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 默认语言，内置的提示信息及未翻译的内容使用该语言
+func DefaultLocale() string {
+	localeLock.RLock()
+	defer localeLock.RUnlock()
+
+	return defaultLocale
+}
+
+// 设置默认语言，如 SetDefaultLocale("en-US")
+func SetDefaultLocale(locale string) {
+	localeLock.Lock()
+	defer localeLock.Unlock()
+
+	defaultLocale = locale
+}
+
+// 已加载的语言
+func Locales() []string {
+	localeLock.RLock()
+	defer localeLock.RUnlock()
+
+	result := make([]string, 0, len(locales))
+	for locale := range locales {
+		result = append(result, locale)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+/**
+ * 获取key在指定语言下的文字，args不为空时作为格式化参数
+ * 语言中没有该key时使用默认语言，都没有时返回key
+ *
+ * 响应码的默认提示使用key "code.<响应码>"，未翻译时使用ErrorCode.Message
+ */
+func Tr(locale, key string, args ...interface{}) string {
+	text, ok := lookupLocale(locale, key)
+	if !ok {
+		text, ok = defaultText(key)
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+func lookupLocale(locale, key string) (string, bool) {
+	localeLock.RLock()
+	defer localeLock.RUnlock()
+
+	text, ok := locales[locale][key]
+	return text, ok
+}
+
+// 默认语言下的文字
+func defaultText(key string) (string, bool) {
+	if text, ok := lookupLocale(DefaultLocale(), key); ok {
+		return text, true
+	}
+
+	if strings.HasPrefix(key, "code.") {
+		if code, err := strconv.Atoi(key[len("code."):]); err == nil {
+			if e, ok := LookupErrorCode(code); ok {
+				return e.Message, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// 使用语言文件中的key的错误，返回响应时按key翻译为请求的语言
+type KeyError struct {
+	Key  string
+	Args []interface{}
+}
+
+func NewKeyError(key string, args ...interface{}) *KeyError {
+	return &KeyError{Key: key, Args: args}
+}
+
+// 默认语言的提示信息
+func (e *KeyError) Error() string {
+	return Tr(DefaultLocale(), e.Key, e.Args...)
+}
+
+/**
+ * 根据请求头Accept-Language选择语言
+ * 按q值从高到低匹配已加载的语言，完整匹配优先，其次匹配主语言(en匹配en-US)
+ *
+ * return 匹配的语言，都不匹配时返回DefaultLocale
+ */
+func MatchLocale(acceptLanguage string) string {
+	type langQ struct {
+		lang string
+		q    float64
+	}
+
+	var langs []langQ
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if v, err := strconv.ParseFloat(field[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			langs = append(langs, langQ{lang, q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	available := Locales()
+	for _, l := range langs {
+		for _, locale := range available {
+			if strings.EqualFold(locale, l.lang) {
+				return locale
+			}
+		}
+		base := strings.SplitN(l.lang, "-", 2)[0]
+		for _, locale := range available {
+			if strings.EqualFold(strings.SplitN(locale, "-", 2)[0], base) {
+				return locale
+			}
+		}
+	}
+
+	return DefaultLocale()
+}
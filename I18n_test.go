@@ -0,0 +1,78 @@
+package commonlib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLocalizeByKey(t *testing.T) {
+	cases := []struct {
+		name     string
+		response *Message
+		expected string
+	}{
+		{"key", NewKeyErrorResponse(CodeDbError, "db.begin", "timeout"), "Database error when starting transaction: timeout"},
+		{"响应码默认提示", NewCodeErrorResponse(CodeNotFound, ""), "Object not found"},
+		{"KeyError", NewCodeErrorResponseFrom(CodeSignError, NewKeyError("sign.duplicate", "orderId")), "Duplicate parameter: orderId"},
+		// 没有key时不按文字反查
+		{"与语言文件相同的文字", NewErrorResponse("签名错误"), "签名错误"},
+		// 没有key时不按前缀猜测格式化参数
+		{"带参数的文字", NewErrorResponse("开启事务时，数据库异常： timeout"), "开启事务时，数据库异常： timeout"},
+		{"无法翻译", NewErrorResponse("自定义错误"), "自定义错误"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if message := c.response.Localize("en-US").Message; message != c.expected {
+				t.Errorf("Localize() message = %s, expected %s", message, c.expected)
+			}
+		})
+	}
+}
+
+func TestLocalizeLegacyMap(t *testing.T) {
+	cases := []struct {
+		name     string
+		res      map[string]interface{}
+		expected string
+	}{
+		{"响应码默认提示", BuildObjectNotFountMessage(), "Object not found"},
+		{"key", NewKeyErrorResponse(CodeDbError, "db.commit", "timeout").ToMap(), "Database error when committing transaction: timeout"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := Localize(c.res, "en-US").(map[string]interface{})
+			if res["message"] != c.expected {
+				t.Errorf("Localize() message = %v, expected %s", res["message"], c.expected)
+			}
+			if _, ok := res["messageKey"]; ok {
+				t.Error("翻译后不应该包含messageKey")
+			}
+		})
+	}
+}
+
+func TestKeyError(t *testing.T) {
+	err := error(NewKeyError("sign.duplicate", "orderId"))
+	if err.Error() != "参数重复: orderId" {
+		t.Errorf("Error() = %s", err.Error())
+	}
+
+	var keyErr *KeyError
+	if !errors.As(err, &keyErr) || keyErr.Key != "sign.duplicate" {
+		t.Errorf("errors.As() = %v", keyErr)
+	}
+}
+
+func TestSetDefaultLocale(t *testing.T) {
+	defer SetDefaultLocale("zh-CN")
+
+	SetDefaultLocale("en-US")
+	if locale := MatchLocale("fr"); locale != "en-US" {
+		t.Errorf("MatchLocale() = %s", locale)
+	}
+	if text := NewKeyError("sign.invalid").Error(); text != "Invalid signature" {
+		t.Errorf("Error() = %s", text)
+	}
+}
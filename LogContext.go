@@ -0,0 +1,115 @@
+package commonlib
+
+import (
+	"context"
+	"github.com/astaxie/beego"
+	beecontext "github.com/astaxie/beego/context"
+)
+
+// context中常用的日志字段
+const (
+	LogKeyRequestId  = "requestId"
+	LogKeyUserId     = "userId"
+	LogKeyScheduleId = "scheduleId"
+	LogKeyChildId    = "childId"
+)
+
+type logFieldsKey struct{}
+
+/**
+ * 返回附加了日志字段的context，LogFrom(ctx)获取的日志会输出这些字段
+ * 已有同名字段时使用新的值
+ *
+ * example:
+ *   ctx = WithLogFields(ctx, StringField(LogKeyScheduleId, scheduleId))
+ *   LogFrom(ctx).Error("排课失败:", err)
+ */
+func WithLogFields(ctx context.Context, fields ...LogField) context.Context {
+	exist := LogFieldsFrom(ctx)
+	merged := make([]LogField, 0, len(exist)+len(fields))
+	for _, field := range exist {
+		if !hasLogField(fields, field.Key) {
+			merged = append(merged, field)
+		}
+	}
+	merged = append(merged, fields...)
+
+	return context.WithValue(ctx, logFieldsKey{}, merged)
+}
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return WithLogFields(ctx, StringField(LogKeyRequestId, requestId))
+}
+
+func WithUserId(ctx context.Context, userId string) context.Context {
+	return WithLogFields(ctx, StringField(LogKeyUserId, userId))
+}
+
+// 替代DebugSchedule，context中的排课ID及学员ID会输出到之后的所有日志
+func WithScheduleId(ctx context.Context, scheduleId, childId string) context.Context {
+	return WithLogFields(ctx, StringField(LogKeyScheduleId, scheduleId), StringField(LogKeyChildId, childId))
+}
+
+// context中的日志字段
+func LogFieldsFrom(ctx context.Context) []LogField {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(logFieldsKey{}).([]LogField)
+	return fields
+}
+
+// context中的请求ID，没有时返回空字符串
+func RequestIdFrom(ctx context.Context) string {
+	for _, field := range LogFieldsFrom(ctx) {
+		if field.Key == LogKeyRequestId {
+			requestId, _ := field.Value.(string)
+			return requestId
+		}
+	}
+	return ""
+}
+
+// 返回输出context中日志字段的日志
+func LogFrom(ctx context.Context) *MyLogger {
+	return Log.WithContext(ctx)
+}
+
+// 返回附加了context中日志字段的日志
+func (log *MyLogger) WithContext(ctx context.Context) *MyLogger {
+	fields := LogFieldsFrom(ctx)
+	if len(fields) == 0 {
+		return log
+	}
+	return log.WithFields(fields...)
+}
+
+func hasLogField(fields []LogField, key string) bool {
+	for _, field := range fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 为请求生成请求ID的beego过滤器，请求头中已有请求ID时使用该ID
+ * 请求ID保存在ctx.Request.Context()中，并通过同名响应头返回
+ * @param header	请求头名称，如 X-Request-Id
+ *
+ * example:
+ *   beego.InsertFilter("*", beego.BeforeRouter, RequestIdFilter("X-Request-Id"))
+ *   LogFrom(c.Ctx.Request.Context()).Error("查询失败:", err)
+ */
+func RequestIdFilter(header string) beego.FilterFunc {
+	return func(ctx *beecontext.Context) {
+		requestId := ctx.Input.Header(header)
+		if requestId == "" {
+			requestId = NewRequestId()
+		}
+
+		ctx.Request = ctx.Request.WithContext(WithRequestId(ctx.Request.Context(), requestId))
+		ctx.Output.Header(header, requestId)
+	}
+}
@@ -0,0 +1,320 @@
+package commonlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	logLevelLock     sync.RWMutex
+	logLevel         = LevelTrace
+	logPackageLevels = map[string]LogLevel{}
+	logLevelOnce     sync.Once
+
+	// 调用方函数地址对应的包名
+	logPackageCache sync.Map
+
+	logSamplerLock sync.RWMutex
+	logSampler     *LogSampler
+)
+
+// 解析日志级别，支持error、warn、info、debug、trace
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	case "trace":
+		return LevelTrace, nil
+	}
+	return LevelTrace, fmt.Errorf("未知的日志级别: %s", level)
+}
+
+// 加载默认配置中的日志级别，见LogOptions.Level、LogOptions.PackageLevels
+func loadLogLevels() {
+	logLevelOnce.Do(func() {
+		setLogLevels(defaultLogOptions())
+	})
+}
+
+// 按配置设置日志级别，Level为空或无法解析时为trace(不过滤，由日志后端决定是否输出)，无法解析的包级别忽略
+func setLogLevels(opts *LogOptions) {
+	level, err := ParseLogLevel(opts.Level)
+	if err != nil {
+		level = LevelTrace
+	}
+
+	packages := make(map[string]LogLevel, len(opts.PackageLevels))
+	for pkg, l := range opts.PackageLevels {
+		if pkgLevel, err := ParseLogLevel(l); err == nil {
+			packages[pkg] = pkgLevel
+		}
+	}
+
+	logLevelLock.Lock()
+	defer logLevelLock.Unlock()
+
+	logLevel = level
+	logPackageLevels = packages
+}
+
+// 按配置设置日志级别及采样，替换默认配置，见ConfigureLogger
+func configureLogFilters(opts *LogOptions) {
+	logLevelOnce.Do(func() {})
+	setLogLevels(opts)
+
+	logSamplerOnce.Do(func() {})
+	SetLogSampler(newLogSampler(opts))
+}
+
+// 设置默认的日志级别，低于该级别的日志不输出
+func SetLogLevel(level LogLevel) {
+	loadLogLevels()
+
+	logLevelLock.Lock()
+	defer logLevelLock.Unlock()
+
+	logLevel = level
+}
+
+/**
+ * 设置包的日志级别，子包使用最接近的上级包的设置
+ * @param pkg	包路径，如 github.com/NateZh/commonlib、main
+ */
+func SetPackageLogLevel(pkg string, level LogLevel) {
+	loadLogLevels()
+
+	logLevelLock.Lock()
+	defer logLevelLock.Unlock()
+
+	logPackageLevels[pkg] = level
+}
+
+// 删除包的日志级别设置，之后使用默认级别
+func ResetPackageLogLevel(pkg string) {
+	loadLogLevels()
+
+	logLevelLock.Lock()
+	defer logLevelLock.Unlock()
+
+	delete(logPackageLevels, pkg)
+}
+
+// 包的日志级别
+func PackageLogLevel(pkg string) LogLevel {
+	loadLogLevels()
+
+	logLevelLock.RLock()
+	defer logLevelLock.RUnlock()
+
+	level, matched := logLevel, ""
+	for prefix, l := range logPackageLevels {
+		if (pkg == prefix || strings.HasPrefix(pkg, prefix+"/")) && len(prefix) > len(matched) {
+			level, matched = l, prefix
+		}
+	}
+
+	return level
+}
+
+// 调用方所在包的日志级别是否允许输出
+func logEnabled(level LogLevel, pc uintptr) bool {
+	return level <= PackageLogLevel(callerPackage(pc))
+}
+
+// 根据函数地址获取包路径，如 github.com/NateZh/commonlib.(*HttpClient).Get 为 github.com/NateZh/commonlib
+func callerPackage(pc uintptr) string {
+	if pkg, ok := logPackageCache.Load(pc); ok {
+		return pkg.(string)
+	}
+
+	pkg := ""
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		name := fn.Name()
+		slash := strings.LastIndex(name, "/")
+		if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+			pkg = name[:slash+1+dot]
+		} else {
+			pkg = name
+		}
+	}
+	logPackageCache.Store(pc, pkg)
+
+	return pkg
+}
+
+/**
+ * 查看及修改日志级别的http接口，用于运行时调整日志级别
+ * GET 返回默认级别及各包的级别
+ * POST/PUT 参数level为级别，package为包路径(为空时修改默认级别)
+ *
+ * 接口本身不做鉴权，由authorize校验请求(如管理员登录态、内网来源)，返回false时响应403
+ * authorize为nil时拒绝所有请求；即使有校验也只应注册在内部管理路由上
+ *
+ * example:
+ *   beego.Handler("/internal/log/level", LogLevelHandler(func(r *http.Request) bool {
+ *     return isAdmin(r)
+ *   }))
+ */
+func LogLevelHandler(authorize func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize == nil || !authorize(r) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(NewCodeErrorResponse(CodeForbidden, ""))
+			return
+		}
+
+		if r.Method == "POST" || r.Method == "PUT" {
+			level, err := ParseLogLevel(r.FormValue("level"))
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(NewCodeErrorResponse(CodeParamsError, err.Error()))
+				return
+			}
+			if pkg := r.FormValue("package"); pkg != "" {
+				SetPackageLogLevel(pkg, level)
+			} else {
+				SetLogLevel(level)
+			}
+		}
+
+		loadLogLevels()
+
+		logLevelLock.RLock()
+		packages := make(map[string]string, len(logPackageLevels))
+		for pkg, level := range logPackageLevels {
+			packages[pkg] = level.String()
+		}
+		content := map[string]interface{}{"level": logLevel.String(), "packages": packages}
+		logLevelLock.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(NewSuccessResponse("", content))
+	})
+}
+
+/**
+ * 重复日志采样，相同级别、相同位置(文件及行号)的日志在每个Interval内只输出前First条，
+ * 之后每Thereafter条输出一条，Thereafter为0时不再输出
+ * 被省略的条数在下一条输出的日志中以字段dropped给出
+ *
+ * example:
+ *   SetLogSampler(&LogSampler{Interval: time.Second, First: 10, Thereafter: 100})
+ */
+type LogSampler struct {
+	Interval   time.Duration
+	First      int
+	Thereafter int
+
+	lock        sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+	dropped     map[string]int
+}
+
+// 判断日志是否输出，返回是否输出及之前省略的条数
+func (s *LogSampler) Allow(key string) (bool, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if s.counts == nil || now.Sub(s.windowStart) >= s.Interval {
+		s.windowStart = now
+		s.counts = make(map[string]int)
+		// 清理很久没有再出现的日志的省略计数
+		if len(s.dropped) > 10000 {
+			s.dropped = nil
+		}
+	}
+	if s.dropped == nil {
+		s.dropped = make(map[string]int)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.First || (s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0) {
+		dropped := s.dropped[key]
+		delete(s.dropped, key)
+		return true, dropped
+	}
+
+	s.dropped[key]++
+	return false, 0
+}
+
+/**
+ * 设置重复日志采样，为nil时不采样
+ * 默认使用LogOptions.SampleInterval、SampleFirst、SampleThereafter
+ */
+func SetLogSampler(sampler *LogSampler) {
+	logSamplerLock.Lock()
+	defer logSamplerLock.Unlock()
+
+	logSampler = sampler
+}
+
+var logSamplerOnce sync.Once
+
+// 根据配置创建采样，SampleInterval为0时返回nil
+func newLogSampler(opts *LogOptions) *LogSampler {
+	if opts.SampleInterval <= 0 {
+		return nil
+	}
+
+	sampler := &LogSampler{Interval: opts.SampleInterval, First: opts.SampleFirst, Thereafter: opts.SampleThereafter}
+	if sampler.First <= 0 {
+		sampler.First = 10
+	}
+	if sampler.Thereafter <= 0 {
+		sampler.Thereafter = 100
+	}
+	return sampler
+}
+
+func currentLogSampler() *LogSampler {
+	logSamplerOnce.Do(func() {
+		sampler := newLogSampler(defaultLogOptions())
+		if sampler == nil {
+			return
+		}
+
+		logSamplerLock.Lock()
+		defer logSamplerLock.Unlock()
+
+		if logSampler == nil {
+			logSampler = sampler
+		}
+	})
+
+	logSamplerLock.RLock()
+	defer logSamplerLock.RUnlock()
+
+	return logSampler
+}
+
+/**
+ * 日志是否按采样输出，按级别及位置计数，内容不同(如包含id)的日志也一起采样
+ * return 是否输出，省略的条数
+ */
+func sampleLog(level LogLevel, file string, line int) (bool, int) {
+	sampler := currentLogSampler()
+	if sampler == nil {
+		return true, 0
+	}
+
+	key := fmt.Sprint(level, ":", file, ":", line)
+	return sampler.Allow(key)
+}
@@ -0,0 +1,126 @@
+package commonlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetLogLevels(t *testing.T) {
+	defer setLogLevels(&LogOptions{})
+
+	cases := []struct {
+		name     string
+		opts     *LogOptions
+		pkg      string
+		expected LogLevel
+	}{
+		{"默认不过滤", &LogOptions{}, "main", LevelTrace},
+		{"无法解析时不过滤", &LogOptions{Level: "verbose"}, "main", LevelTrace},
+		{"默认级别", &LogOptions{Level: "warn"}, "main", LevelWarn},
+		{"包级别", &LogOptions{PackageLevels: map[string]string{"main": "debug"}}, "main", LevelDebug},
+		{"子包", &LogOptions{PackageLevels: map[string]string{"a/b": "error"}}, "a/b/c", LevelError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setLogLevels(c.opts)
+			if level := PackageLogLevel(c.pkg); level != c.expected {
+				t.Errorf("PackageLogLevel(%s) = %s, expected %s", c.pkg, level, c.expected)
+			}
+		})
+	}
+}
+
+type recordLogger struct {
+	records []*LogRecord
+}
+
+func (l *recordLogger) Write(record *LogRecord) {
+	l.records = append(l.records, record)
+}
+
+func TestDefaultLogLevel(t *testing.T) {
+	defer setLogLevels(&LogOptions{})
+
+	// 没有配置logLevel时Debug、Trace日志照常输出
+	setLogLevels(BeegoLogOptions())
+
+	backend := new(recordLogger)
+	log := &MyLogger{Backend: backend}
+	log.Debug("debug")
+	log.Trace("trace")
+	log.DebugSchedule("1", "2", "schedule")
+
+	if len(backend.records) != 3 {
+		t.Fatalf("输出了%d条日志，expected 3", len(backend.records))
+	}
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	defer setLogLevels(&LogOptions{})
+
+	allow := func(r *http.Request) bool { return r.Header.Get("X-Admin") == "1" }
+
+	cases := []struct {
+		name      string
+		authorize func(r *http.Request) bool
+		admin     bool
+		status    int
+	}{
+		{"没有校验方法", nil, true, http.StatusForbidden},
+		{"校验失败", allow, false, http.StatusForbidden},
+		{"校验通过", allow, true, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setLogLevels(&LogOptions{})
+
+			request := httptest.NewRequest("POST", "/internal/log/level?level=info", nil)
+			if c.admin {
+				request.Header.Set("X-Admin", "1")
+			}
+			recorder := httptest.NewRecorder()
+			LogLevelHandler(c.authorize).ServeHTTP(recorder, request)
+
+			if recorder.Code != c.status {
+				t.Fatalf("status = %d, expected %d", recorder.Code, c.status)
+			}
+			expected := LevelTrace
+			if c.status == http.StatusOK {
+				expected = LevelInfo
+			}
+			if level := PackageLogLevel("main"); level != expected {
+				t.Errorf("level = %s, expected %s", level, expected)
+			}
+		})
+	}
+}
+
+func TestSampleLog(t *testing.T) {
+	defer SetLogSampler(nil)
+	SetLogSampler(&LogSampler{Interval: time.Hour, First: 2, Thereafter: 3})
+
+	// 同一位置内容不同的日志一起计数
+	var allowed []bool
+	for i := 0; i < 5; i++ {
+		allow, _ := sampleLog(LevelError, "a.go", 10)
+		allowed = append(allowed, allow)
+	}
+	expected := []bool{true, true, false, false, true}
+	for i := range expected {
+		if allowed[i] != expected[i] {
+			t.Fatalf("sampleLog() = %v, expected %v", allowed, expected)
+		}
+	}
+
+	// 不同位置、不同级别分别计数
+	if allow, _ := sampleLog(LevelError, "a.go", 11); !allow {
+		t.Error("不同行的日志被省略")
+	}
+	if allow, _ := sampleLog(LevelWarn, "a.go", 10); !allow {
+		t.Error("不同级别的日志被省略")
+	}
+}
@@ -0,0 +1,255 @@
+package commonlib
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * 按大小或日期切分的日志文件
+ * 切分时当前文件重命名为 <Path>.<时间>，之后按MaxBackups/MaxAge删除旧文件，Compress为true时压缩为.gz
+ *
+ * example:
+ *   writer, err := NewRotateWriter("logs/app.log")
+ *   writer.MaxSize = 100 << 20
+ *   writer.MaxBackups = 30
+ *   writer.Compress = true
+ *   SetLogger(NewWriterLogger(writer, LogFormatJSON))
+ */
+type RotateWriter struct {
+	Path string
+
+	// 文件达到该字节数时切分，0表示不按大小切分
+	MaxSize int64
+
+	// 每天切分一次
+	Daily bool
+
+	// 最多保留的切分文件数，0表示不限制
+	MaxBackups int
+
+	// 切分文件的保留时间，0表示不限制
+	MaxAge time.Duration
+
+	// 使用gzip压缩切分文件
+	Compress bool
+
+	lock      sync.Mutex
+	cleanLock sync.Mutex
+	file      *os.File
+	size      int64
+	openedAt  time.Time
+}
+
+// 日志切分文件名中的时间格式
+const rotateTimeFormat = "20060102-150405.000"
+
+func NewRotateWriter(path string) (*RotateWriter, error) {
+	w := &RotateWriter{Path: path}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *RotateWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+// 立即切分当前文件
+func (w *RotateWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.rotate()
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = info.ModTime()
+	if w.size == 0 {
+		w.openedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (w *RotateWriter) shouldRotate(writeSize int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.MaxSize > 0 && w.size+writeSize > w.MaxSize {
+		return true
+	}
+	if w.Daily {
+		now := time.Now()
+		y1, m1, d1 := w.openedAt.Date()
+		y2, m2, d2 := now.Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
+
+func (w *RotateWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	backup := w.Path + "." + time.Now().Format(rotateTimeFormat)
+	if err := os.Rename(w.Path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	// 压缩及清理可能较慢，不阻塞日志写入
+	go w.cleanup(backup)
+
+	return nil
+}
+
+func (w *RotateWriter) cleanup(backup string) {
+	w.cleanLock.Lock()
+	defer w.cleanLock.Unlock()
+
+	if w.Compress {
+		if err := gzipFile(backup); err != nil {
+			Log.Warn("压缩日志文件发生错误:", err)
+		}
+	}
+
+	if w.MaxBackups <= 0 && w.MaxAge <= 0 {
+		return
+	}
+
+	backups, err := filepath.Glob(w.Path + ".*")
+	if err != nil {
+		return
+	}
+	// 文件名中的时间按字符串排序即为时间顺序，最新的在前
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	now := time.Now()
+	kept := 0
+	for _, path := range backups {
+		// 只处理 <Path>.<时间>[.gz] 格式的切分文件
+		if !isRotateSuffix(path[len(w.Path)+1:]) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		expired := w.MaxAge > 0 && now.Sub(info.ModTime()) > w.MaxAge
+		if expired || (w.MaxBackups > 0 && kept >= w.MaxBackups) {
+			os.Remove(path)
+			continue
+		}
+		kept++
+	}
+}
+
+// 是否为rotate生成的切分文件后缀，格式为rotateTimeFormat，压缩后带.gz
+func isRotateSuffix(suffix string) bool {
+	_, err := time.Parse(rotateTimeFormat, strings.TrimSuffix(suffix, ".gz"))
+	return err == nil
+}
+
+// 压缩为<path>.gz后删除原文件
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tempPath := path + ".gz.tmp"
+	dst, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(dst)
+	_, err = io.Copy(writer, src)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err = os.Rename(tempPath, path+".gz"); err != nil {
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,61 @@
+package commonlib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsRotateSuffix(t *testing.T) {
+	cases := []struct {
+		suffix   string
+		expected bool
+	}{
+		{"20240102-030405.000", true},
+		{"20240102-030405.123.gz", true},
+		{"20240102-030405.123.gz.tmp", false},
+		{"2024-backup", false},
+		{"1", false},
+		{"20240102", false},
+		{"old", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.suffix, func(t *testing.T) {
+			if valid := isRotateSuffix(c.suffix); valid != c.expected {
+				t.Errorf("isRotateSuffix(%q) = %v, expected %v", c.suffix, valid, c.expected)
+			}
+		})
+	}
+}
+
+func TestRotateWriterCleanup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	files := []string{
+		"app.log.20240101-000000.000.gz",
+		"app.log.20240102-000000.000",
+		"app.log.20240103-000000.000",
+		"app.log.2024-backup",
+		"app.log.1",
+	}
+	for _, name := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := &RotateWriter{Path: path, MaxBackups: 1}
+	writer.cleanup(filepath.Join(dir, "app.log.20240103-000000.000"))
+
+	for i, name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		// 保留最新的一个切分文件，其他格式的文件不处理
+		kept := i >= 2
+		if (err == nil) != kept {
+			t.Errorf("%s 存在 = %v, expected %v", name, err == nil, kept)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package commonlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志记录
+type LogRecord struct {
+	Time    time.Time
+	Level   LogLevel
+	File    string // 调用方文件
+	Line    int    // 调用方行号
+	Message string
+	Fields  []LogField
+}
+
+// 日志输出后端
+type Logger interface {
+	Write(record *LogRecord)
+}
+
+// 日志配置，用于ConfigureLogger及LoggerFactory
+type LogOptions struct {
+	// 日志后端: stdout(默认)、file、beego、slog(需要go1.21及以上)或RegisterLogger注册的名称
+	Backend string
+
+	// 输出格式: text(默认)或json，用于stdout、file后端
+	Format string
+
+	// file后端的文件路径，默认logs/app.log
+	File string
+
+	// file后端的切分规则，见RotateWriter
+	MaxSize    int64
+	Daily      bool
+	MaxBackups int
+	MaxAge     time.Duration
+	Compress   bool
+
+	// 默认日志级别: error、warn、info、debug、trace，为空时不过滤(trace)，由日志后端决定是否输出
+	Level string
+
+	// 各包的日志级别，如 {"main": "debug"}，见SetPackageLogLevel
+	PackageLevels map[string]string
+
+	// 重复日志采样，SampleInterval为0时不采样，SampleFirst默认10，SampleThereafter默认100，见LogSampler
+	SampleInterval   time.Duration
+	SampleFirst      int
+	SampleThereafter int
+}
+
+// 日志后端创建方法
+type LoggerFactory func(opts *LogOptions) (Logger, error)
+
+var (
+	loggerFactoryLock sync.RWMutex
+	loggerFactories   = map[string]LoggerFactory{
+		"stdout": func(opts *LogOptions) (Logger, error) {
+			return NewStdoutLogger(opts.Format), nil
+		},
+		"file": newFileLoggerWithOptions,
+	}
+
+	defaultLoggerLock sync.RWMutex
+	defaultLogger     Logger
+
+	// 没有调用SetLogger/ConfigureLogger时获取日志配置的方法，见BeegoLogOptions
+	defaultLogOptions = func() *LogOptions {
+		return &LogOptions{}
+	}
+)
+
+/**
+ * 注册日志后端，用于LogOptions.Backend选择
+ * @param name		后端名称
+ * @param factory	创建方法
+ */
+func RegisterLogger(name string, factory LoggerFactory) {
+	loggerFactoryLock.Lock()
+	defer loggerFactoryLock.Unlock()
+
+	loggerFactories[name] = factory
+}
+
+/**
+ * 根据配置创建日志后端
+ * @param opts	日志配置，为nil时使用默认配置
+ */
+func NewLogger(opts *LogOptions) (Logger, error) {
+	if opts == nil {
+		opts = &LogOptions{}
+	}
+	name := opts.Backend
+	if name == "" {
+		name = "stdout"
+	}
+
+	loggerFactoryLock.RLock()
+	factory, ok := loggerFactories[name]
+	loggerFactoryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("未知的日志后端: %s", name)
+	}
+	return factory(opts)
+}
+
+/**
+ * 根据配置创建日志后端并设置为默认后端，同时按配置设置日志级别及采样
+ *
+ * example:
+ *   err := ConfigureLogger(&LogOptions{Backend: "file", Format: LogFormatJSON, MaxSize: 100 << 20, Compress: true})
+ */
+func ConfigureLogger(opts *LogOptions) error {
+	if opts == nil {
+		opts = &LogOptions{}
+	}
+
+	logger, err := NewLogger(opts)
+	if err != nil {
+		return err
+	}
+
+	SetLogger(logger)
+	configureLogFilters(opts)
+
+	return nil
+}
+
+/**
+ * 设置默认的日志后端，Log及没有指定Backend的MyLogger都使用该后端
+ *
+ * example:
+ *   SetLogger(NewStdoutLogger(LogFormatJSON))
+ */
+func SetLogger(logger Logger) {
+	defaultLoggerLock.Lock()
+	defer defaultLoggerLock.Unlock()
+
+	defaultLogger = logger
+}
+
+/**
+ * 默认的日志后端，没有调用SetLogger/ConfigureLogger时在第一次输出日志时根据defaultLogOptions创建
+ * 创建失败时输出到标准错误
+ */
+func configuredLogger() Logger {
+	defaultLoggerLock.RLock()
+	logger := defaultLogger
+	defaultLoggerLock.RUnlock()
+
+	if logger != nil {
+		return logger
+	}
+
+	defaultLoggerLock.Lock()
+	defer defaultLoggerLock.Unlock()
+
+	if defaultLogger == nil {
+		var err error
+		if defaultLogger, err = NewLogger(defaultLogOptions()); err != nil {
+			fmt.Fprintln(os.Stderr, "创建日志后端失败，输出到标准错误:", err)
+			defaultLogger = NewWriterLogger(os.Stderr, LogFormatText)
+		}
+	}
+
+	return defaultLogger
+}
+
+// 输出到io.Writer的日志，格式为text或json
+type WriterLogger struct {
+	Writer io.Writer
+	Format string
+
+	lock sync.Mutex
+}
+
+func NewWriterLogger(writer io.Writer, format string) *WriterLogger {
+	return &WriterLogger{Writer: writer, Format: format}
+}
+
+// 输出到标准输出
+func NewStdoutLogger(format string) *WriterLogger {
+	return NewWriterLogger(os.Stdout, format)
+}
+
+// 输出到文件，文件不存在时创建，Writer为*RotateWriter，可设置切分规则
+func NewFileLogger(path, format string) (*WriterLogger, error) {
+	writer, err := NewRotateWriter(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriterLogger(writer, format), nil
+}
+
+// 根据配置创建file后端
+func newFileLoggerWithOptions(opts *LogOptions) (Logger, error) {
+	path := opts.File
+	if path == "" {
+		path = "logs/app.log"
+	}
+
+	logger, err := NewFileLogger(path, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := logger.Writer.(*RotateWriter)
+	writer.MaxSize = opts.MaxSize
+	writer.Daily = opts.Daily
+	writer.MaxBackups = opts.MaxBackups
+	writer.MaxAge = opts.MaxAge
+	writer.Compress = opts.Compress
+
+	return logger, nil
+}
+
+func (l *WriterLogger) Write(record *LogRecord) {
+	var data []byte
+	if l.Format == LogFormatJSON {
+		data = formatLogJSON(record)
+	} else {
+		data = formatLogText(record)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.Writer.Write(data)
+}
+
+// 关闭文件，Writer不是io.Closer时不做处理
+func (l *WriterLogger) Close() error {
+	if closer, ok := l.Writer.(io.Closer); ok && l.Writer != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+var logLevelTags = map[LogLevel]string{
+	LevelError: "[E]",
+	LevelWarn:  "[W]",
+	LevelInfo:  "[I]",
+	LevelDebug: "[D]",
+	LevelTrace: "[T]",
+}
+
+// text格式，与beego的日志格式一致: 2006/01/02 15:04:05.000 [E] (文件:...,行:...) 内容 key=value
+func formatLogText(record *LogRecord) []byte {
+	var builder strings.Builder
+	builder.WriteString(record.Time.Format("2006/01/02 15:04:05.000"))
+	builder.WriteString(" " + logLevelTags[record.Level] + " ")
+	builder.WriteString("(文件: " + record.File + " ,行: " + strconv.Itoa(record.Line) + " ) ")
+	builder.WriteString(record.Message)
+	builder.WriteString(formatLogFields(record.Fields))
+	builder.WriteString("\n")
+
+	return []byte(builder.String())
+}
+
+// json格式，每条日志一行
+func formatLogJSON(record *LogRecord) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, record.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, record.Level.String())
+	buf.WriteString(`,"file":`)
+	writeJSONValue(buf, record.File)
+	buf.WriteString(`,"line":`)
+	buf.WriteString(strconv.Itoa(record.Line))
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, record.Message)
+	for _, field := range record.Fields {
+		buf.WriteByte(',')
+		writeJSONValue(buf, field.Key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, logFieldValue(field.Value))
+	}
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
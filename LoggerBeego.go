@@ -0,0 +1,78 @@
+package commonlib
+
+import (
+	"github.com/astaxie/beego"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterLogger("beego", func(*LogOptions) (Logger, error) {
+		return NewBeegoLogger(), nil
+	})
+	defaultLogOptions = BeegoLogOptions
+}
+
+/**
+ * 从beego配置读取日志配置，作为没有调用SetLogger/ConfigureLogger时的默认配置
+ * 配置项: logBackend(默认beego)、logFormat(text/json)、logFile(默认logs/app.log)、
+ * logMaxSize(MB)、logDaily、logMaxBackups、logMaxDays、logCompress、
+ * logLevel(默认不过滤，由beego的日志级别决定)、logPackageLevels(如 main=debug,github.com/NateZh/commonlib=warn)、
+ * logSampleInterval(秒，为0时不采样)、logSampleFirst(默认10)、logSampleThereafter(默认100)
+ */
+func BeegoLogOptions() *LogOptions {
+	return &LogOptions{
+		Backend:    beego.AppConfig.DefaultString("logBackend", "beego"),
+		Format:     beego.AppConfig.DefaultString("logFormat", LogFormatText),
+		File:       beego.AppConfig.DefaultString("logFile", "logs/app.log"),
+		MaxSize:    beego.AppConfig.DefaultInt64("logMaxSize", 0) << 20,
+		Daily:      beego.AppConfig.DefaultBool("logDaily", false),
+		MaxBackups: beego.AppConfig.DefaultInt("logMaxBackups", 0),
+		MaxAge:     time.Duration(beego.AppConfig.DefaultInt("logMaxDays", 0)) * 24 * time.Hour,
+		Compress:   beego.AppConfig.DefaultBool("logCompress", false),
+
+		Level:         beego.AppConfig.String("logLevel"),
+		PackageLevels: parsePackageLevels(beego.AppConfig.String("logPackageLevels")),
+
+		SampleInterval:   time.Duration(beego.AppConfig.DefaultInt("logSampleInterval", 0)) * time.Second,
+		SampleFirst:      beego.AppConfig.DefaultInt("logSampleFirst", 10),
+		SampleThereafter: beego.AppConfig.DefaultInt("logSampleThereafter", 100),
+	}
+}
+
+// 解析 包路径=级别 以逗号分隔的配置
+func parsePackageLevels(config string) map[string]string {
+	levels := make(map[string]string)
+	for _, item := range strings.Split(config, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		levels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return levels
+}
+
+// 通过beego的日志输出，日志级别及输出位置由beego的配置决定
+type BeegoLogger struct{}
+
+func NewBeegoLogger() *BeegoLogger {
+	return new(BeegoLogger)
+}
+
+func (l *BeegoLogger) Write(record *LogRecord) {
+	text := record.Message + formatLogFields(record.Fields)
+
+	switch record.Level {
+	case LevelError:
+		beego.Error("(文件:", record.File, ",行:", record.Line, ")", text)
+	case LevelWarn:
+		beego.Warn("(文件:", record.File, ",行:", record.Line, ")", text)
+	case LevelInfo:
+		beego.Info("(文件:", record.File, ",行:", record.Line, ")", text)
+	case LevelDebug:
+		beego.Debug("(文件:", record.File, ",行:", record.Line, ")", text)
+	default:
+		beego.Trace("(文件:", record.File, ",行:", record.Line, ")", text)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build go1.21
+// +build go1.21
+
+package commonlib
+
+import (
+	"context"
+	"log/slog"
+)
+
+func init() {
+	RegisterLogger("slog", func(*LogOptions) (Logger, error) {
+		return NewSlogLogger(slog.Default()), nil
+	})
+}
+
+// trace级别在slog中的级别
+const SlogLevelTrace = slog.LevelDebug - 4
+
+// 通过log/slog输出，调用方文件及行号作为file、line字段
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{Logger: logger}
+}
+
+func (l *SlogLogger) Write(record *LogRecord) {
+	level := SlogLevelTrace
+	switch record.Level {
+	case LevelError:
+		level = slog.LevelError
+	case LevelWarn:
+		level = slog.LevelWarn
+	case LevelInfo:
+		level = slog.LevelInfo
+	case LevelDebug:
+		level = slog.LevelDebug
+	}
+
+	ctx := context.Background()
+	if !l.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(record.Fields)+2)
+	attrs = append(attrs, slog.String("file", record.File), slog.Int("line", record.Line))
+	for _, field := range record.Fields {
+		attrs = append(attrs, slog.Any(field.Key, logFieldValue(field.Value)))
+	}
+
+	l.Logger.LogAttrs(ctx, level, record.Message, attrs...)
+}
@@ -0,0 +1,49 @@
+package commonlib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigureLogger(t *testing.T) {
+	defer SetLogger(nil)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := ConfigureLogger(&LogOptions{Backend: "file", Format: LogFormatJSON, File: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	Log.Error("configured", StringField("requestId", "r1"))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"msg":"configured"`) || !strings.Contains(string(data), `"requestId":"r1"`) {
+		t.Errorf("日志内容 = %s", data)
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  *LogOptions
+		valid bool
+	}{
+		{"nil", nil, true},
+		{"stdout", &LogOptions{Backend: "stdout"}, true},
+		{"beego", &LogOptions{Backend: "beego"}, true},
+		{"未知的后端", &LogOptions{Backend: "unknown"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			logger, err := NewLogger(c.opts)
+			if (err == nil) != c.valid || (c.valid && logger == nil) {
+				t.Errorf("NewLogger() = %v, %v", logger, err)
+			}
+		})
+	}
+}
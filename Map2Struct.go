@@ -132,41 +132,70 @@ func Map2Struct(data map[string]string, s interface{}) (err error) {
 	return err
 }
 
-// 类型转换
+// time.Time支持的格式
+var timeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339}
+
+/**
+ * 类型转换
+ * @param value	字符串值
+ * @param ntype	类型名，如 int8、uint、float64、time.Time
+ *
+ * 整数超出类型范围时返回错误，time.Time支持 2006-01-02 15:04:05、2006-01-02 及RFC3339格式
+ */
 func TypeConversion(value string, ntype string) (reflect.Value, error) {
 	var res reflect.Value
 	var err error
 	var t time.Time
 	var i int
 	var i64 int64
+	var u64 uint64
 	var f64 float64
 	switch ntype {
 	case "string":
 		res = reflect.ValueOf(value)
-	case "time.Time":
-		t, err = time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
-		res = reflect.ValueOf(t)
-	case "Time":
-		t, err = time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	case "time.Time", "Time":
+		t, err = parseTime(value)
 		res = reflect.ValueOf(t)
 	case "int":
 		i, err = strconv.Atoi(value)
 		res = reflect.ValueOf(i)
 	case "int8":
-		i64, err = strconv.ParseInt(value, 10, 64)
+		i64, err = strconv.ParseInt(value, 10, 8)
 		res = reflect.ValueOf(int8(i64))
+	case "int16":
+		i64, err = strconv.ParseInt(value, 10, 16)
+		res = reflect.ValueOf(int16(i64))
 	case "int32":
-		i64, err = strconv.ParseInt(value, 10, 64)
+		i64, err = strconv.ParseInt(value, 10, 32)
 		res = reflect.ValueOf(int32(i64))
 	case "int64":
 		i64, err = strconv.ParseInt(value, 10, 64)
-		res = reflect.ValueOf(int64(i64))
+		res = reflect.ValueOf(i64)
+	case "uint":
+		u64, err = strconv.ParseUint(value, 10, 0)
+		res = reflect.ValueOf(uint(u64))
+	case "uint8":
+		u64, err = strconv.ParseUint(value, 10, 8)
+		res = reflect.ValueOf(uint8(u64))
+	case "uint16":
+		u64, err = strconv.ParseUint(value, 10, 16)
+		res = reflect.ValueOf(uint16(u64))
+	case "uint32":
+		u64, err = strconv.ParseUint(value, 10, 32)
+		res = reflect.ValueOf(uint32(u64))
+	case "uint64":
+		u64, err = strconv.ParseUint(value, 10, 64)
+		res = reflect.ValueOf(u64)
 	case "float32":
-		f64, err = strconv.ParseFloat(value, 64)
+		f64, err = strconv.ParseFloat(value, 32)
 		res = reflect.ValueOf(float32(f64))
 	case "float64":
 		f64, err = strconv.ParseFloat(value, 64)
 		res = reflect.ValueOf(float64(f64))
+	case "bool":
+		var b bool
+		b, err = strconv.ParseBool(value)
+		res = reflect.ValueOf(b)
 		// 根据实际需要补充更多类型的处理
 	default:
 		err = errors.New("未知的类型：" + ntype)
@@ -178,3 +207,15 @@ func TypeConversion(value string, ntype string) (reflect.Value, error) {
 
 	return res, err
 }
+
+// 按timeLayouts依次解析时间，没有时区的格式使用本地时区
+func parseTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
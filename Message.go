@@ -1,92 +1,350 @@
 package commonlib
 
 import (
-	"math"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
-type Message struct {
-	Result  string      `required:"true"  description:"success/fail"`
-	Message string      `required:"true"  description:"文字信息提示"`
-	Data    interface{} `required:"false" description:"如果发生错误，该属性不出现，正常情况下为具体的数据结构"`
-	Pager   interface{} `required:"false" description:"如果没有分页数据，该属性不出现"`
+// 统一的响应格式，json格式为 {"code":0,"message":"","content":{},"pager":{}}
+type Response[T any] struct {
+	Code    int           `json:"code"    required:"true"  description:"0表示成功，其他表示失败"`
+	Message string        `json:"message" required:"true"  description:"文字信息提示"`
+	Content T             `json:"content"           required:"false" description:"如果发生错误，该属性不出现，正常情况下为具体的数据结构"`
+	Pager   *Pager        `json:"pager,omitempty"   required:"false" description:"如果没有分页数据，该属性不出现"`
+	Errors  []*FieldError `json:"errors,omitempty" required:"false" description:"参数错误时各字段的错误信息，没有时该属性不出现"`
+
+	// 提示信息在语言文件中的key及格式化参数，设置后Localize使用key翻译Message
+	MessageKey  string        `json:"-"`
+	MessageArgs []interface{} `json:"-"`
+}
+
+// 内容类型不确定时使用的响应格式
+type Message = Response[interface{}]
+
+// 响应中code不为0时返回的错误
+type ResponseError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("接口返回错误(code:%d): %s", e.Code, e.Message)
+}
+
+// 字段错误信息，Field为请求参数名
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	// 提示信息在语言文件中的key及格式化参数
+	Key  string        `json:"-"`
+	Args []interface{} `json:"-"`
 }
 
-type Pager struct {
-	PageId     int `json:"pageId"`
-	RecPerPage int `json:"recPerPage"`
-	Total      int `json:"total"`
+// 使用语言文件中的key创建字段错误信息
+func NewFieldError(field, key string, args ...interface{}) *FieldError {
+	return &FieldError{Field: field, Message: Tr(DefaultLocale(), key, args...), Key: key, Args: args}
+}
+
+// 翻译为指定语言，没有Key时保留原文
+func (e *FieldError) Localize(locale string) *FieldError {
+	res := *e
+	if e.Key != "" {
+		res.Message = Tr(locale, e.Key, e.Args...)
+	}
+	return &res
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// 多个字段的错误信息
+type FieldErrors []*FieldError
+
+func (errs FieldErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "; ")
 }
 
-func buildMessage(result bool, message string, data interface{}, pager *Pager) map[string]interface{} {
+// message为空时使用响应码的默认提示(BuildSuccessMessage为空字符串)
+func NewSuccessResponse[T any](message string, content T) *Response[T] {
+	return newResponse(CodeSuccess, message, content, nil)
+}
+
+func NewPageResponse[T any](message string, content T, pager *Pager) *Response[T] {
+	return newResponse(CodeSuccess, message, content, pager)
+}
+
+func NewErrorResponse(message string) *Message {
+	return NewCodeErrorResponse(CodeFail, message)
+}
+
+// 使用错误码创建失败响应，message为空时使用错误码的默认提示
+func NewCodeErrorResponse(code int, message string) *Message {
+	return newResponse[interface{}](code, message, nil, nil)
+}
+
+func newResponse[T any](code int, message string, content T, pager *Pager) *Response[T] {
+	res := &Response[T]{Code: code, Message: codeMessage(code, message), Content: content, Pager: pager}
+	if message == "" {
+		res.MessageKey = "code." + strconv.Itoa(code)
+	}
+	return res
+}
+
+// 使用语言文件中的key创建失败响应，返回时通过Localize翻译为请求的语言
+func NewKeyErrorResponse(code int, key string, args ...interface{}) *Message {
+	return &Message{Code: code, Message: Tr(DefaultLocale(), key, args...), MessageKey: key, MessageArgs: args}
+}
+
+// 创建参数错误响应，errs为各字段的错误信息
+func NewParamsErrorResponse(errs ...*FieldError) *Message {
+	res := NewCodeErrorResponse(CodeParamsError, "")
+	res.Errors = errs
+	return res
+}
+
+/**
+ * 根据error创建失败响应
+ * err为*ResponseError时使用其响应码，为FieldErrors/*FieldError时返回参数错误响应，否则使用CodeFail
+ * err为*KeyError时使用其key翻译提示信息
+ */
+func NewErrorResponseFrom(err error) *Message {
+	var codeErr *ResponseError
+	if errors.As(err, &codeErr) {
+		return NewCodeErrorResponse(codeErr.Code, codeErr.Message)
+	}
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return NewParamsErrorResponse(fieldErrs...)
+	}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return NewParamsErrorResponse(fieldErr)
+	}
+	return NewCodeErrorResponseFrom(CodeFail, err)
+}
+
+// 使用错误码及error创建失败响应，err为*KeyError时使用其key翻译提示信息
+func NewCodeErrorResponseFrom(code int, err error) *Message {
+	var keyErr *KeyError
+	if errors.As(err, &keyErr) {
+		return NewKeyErrorResponse(code, keyErr.Key, keyErr.Args...)
+	}
+	return NewCodeErrorResponse(code, err.Error())
+}
+
+// json中各属性的顺序及是否出现
+type responseJSON struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Content interface{}   `json:"content,omitempty"`
+	Pager   *Pager        `json:"pager,omitempty"`
+	Errors  []*FieldError `json:"errors,omitempty"`
+}
+
+/**
+ * 与buildMessage一致，只有Content为nil(如失败响应的Message)时不输出content，
+ * 空切片、0、false等值照常输出
+ */
+func (r Response[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&responseJSON{
+		Code:    r.Code,
+		Message: r.Message,
+		Content: interface{}(r.Content),
+		Pager:   r.Pager,
+		Errors:  r.Errors,
+	})
+}
+
+// 是否成功
+func (r *Response[T]) Success() bool {
+	return r.Code == CodeSuccess
+}
+
+// 失败时返回*ResponseError，成功时返回nil
+func (r *Response[T]) Err() error {
+	if r.Success() {
+		return nil
+	}
+	return &ResponseError{Code: r.Code, Message: r.Message}
+}
+
+/**
+ * 转换为map，用于兼容返回map[string]interface{}的方法
+ * MessageKey不是响应码的默认提示时以messageKey、messageArgs保存，Localize翻译map时使用并移除
+ */
+func (r *Response[T]) ToMap() map[string]interface{} {
 	msg := make(map[string]interface{})
 
-	if result {
-		msg["code"] = 0
-	} else {
-		msg["code"] = -1
+	msg["code"] = r.Code
+
+	if interface{}(r.Content) != nil {
+		msg["content"] = r.Content
 	}
+	msg["message"] = r.Message
 
-	if data != nil {
-		msg["content"] = data
+	if r.Pager != nil {
+		msg["pager"] = r.Pager
 	}
-	msg["message"] = message
 
-	if pager != nil {
-		msg["pager"] = pager
+	if len(r.Errors) > 0 {
+		msg["errors"] = r.Errors
+	}
+
+	if r.MessageKey != "" && r.MessageKey != "code."+strconv.Itoa(r.Code) {
+		msg["messageKey"] = r.MessageKey
+		if len(r.MessageArgs) > 0 {
+			msg["messageArgs"] = r.MessageArgs
+		}
 	}
 
 	return msg
 }
 
-func buildPager(pageId, recPerPage, total int) *Pager {
+/**
+ * 返回翻译为指定语言的响应
+ * 设置了MessageKey时使用key翻译，Message为响应码的默认提示时使用响应码翻译，
+ * 否则保留原文(不按文字反查key)
+ *
+ * example:
+ *   res.Localize(MatchLocale(ctx.Input.Header("Accept-Language")))
+ */
+func (r *Response[T]) Localize(locale string) *Response[T] {
+	res := *r
+	if r.MessageKey != "" {
+		res.Message = Tr(locale, r.MessageKey, r.MessageArgs...)
+	} else {
+		res.Message = translateCodeMessage(r.Code, r.Message, locale)
+	}
+	res.Errors = localizeFieldErrors(r.Errors, locale)
+	return &res
+}
 
-	if pageId < 1 {
-		pageId = 1
+// 翻译没有key的提示信息，只有响应码的默认提示可以翻译
+func translateCodeMessage(code int, message, locale string) string {
+	if message != "" && message == codeMessage(code, "") {
+		return Tr(locale, "code."+strconv.Itoa(code))
 	}
+	return message
+}
 
-	if recPerPage < 1 {
-		recPerPage = 10
+func localizeFieldErrors(errs []*FieldError, locale string) []*FieldError {
+	if len(errs) == 0 {
+		return errs
+	}
+	localized := make([]*FieldError, 0, len(errs))
+	for _, e := range errs {
+		localized = append(localized, e.Localize(locale))
 	}
+	return localized
+}
 
-	totalPage := int(math.Ceil(float64(total) / float64(recPerPage)))
+/**
+ * 解析其他服务返回的响应
+ * @param data	响应内容
+ *
+ * return 响应， 错误信息(格式错误或code不为0时返回*ResponseError)
+ *
+ * example:
+ *   res, err := ParseResponse[[]Schedule](body)
+ *   list := res.Content
+ */
+func ParseResponse[T any](data []byte) (*Response[T], error) {
+	res := new(Response[T])
 
-	if pageId > totalPage {
-		pageId = totalPage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(res); err != nil {
+		return nil, fmt.Errorf("响应格式错误: %v", err)
 	}
 
-	if total == 0 {
-		pageId = 1
-	}
+	return res, res.Err()
+}
+
+// 兼容的map响应，message原样输出，为空时不使用响应码的默认提示
+func buildMessage(code int, message string, data interface{}, pager *Pager) map[string]interface{} {
+	res := newResponse(code, message, data, pager)
+	res.Message = message
+	return res.ToMap()
+}
 
-	p := new(Pager)
+/**
+ * 翻译响应中的提示信息，支持*Response及Build*Message返回的map
+ * 其他类型原样返回
+ */
+func Localize(v interface{}, locale string) interface{} {
+	switch res := v.(type) {
+	case interface{ localize(string) interface{} }:
+		return res.localize(locale)
+	case map[string]interface{}:
+		message, ok := res["message"].(string)
+		if !ok {
+			return res
+		}
+		localized := make(map[string]interface{}, len(res))
+		for key, val := range res {
+			localized[key] = val
+		}
+		if key, ok := res["messageKey"].(string); ok {
+			args, _ := res["messageArgs"].([]interface{})
+			localized["message"] = Tr(locale, key, args...)
+			delete(localized, "messageKey")
+			delete(localized, "messageArgs")
+		} else {
+			code, _ := res["code"].(int)
+			localized["message"] = translateCodeMessage(code, message, locale)
+		}
+		if errs, ok := res["errors"].([]*FieldError); ok {
+			localized["errors"] = localizeFieldErrors(errs, locale)
+		}
+		return localized
+	}
+	return v
+}
 
-	p.PageId = pageId
-	p.RecPerPage = recPerPage
-	p.Total = total
+func (r *Response[T]) localize(locale string) interface{} {
+	return r.Localize(locale)
+}
 
-	return p
+func (r *Response[T]) responseCode() int {
+	return r.Code
 }
 
+// 以下方法保留用于兼容，新代码使用NewSuccessResponse/NewPageResponse/NewCodeErrorResponse
 func BuildSuccessMessage(message string, data interface{}) map[string]interface{} {
-	return buildMessage(true, message, data, nil)
+	return buildMessage(CodeSuccess, message, data, nil)
 }
 
 func BuildSuccessPageMessage(message string, data interface{}, pager *Pager) map[string]interface{} {
-	return buildMessage(true, message, data, pager)
+	return buildMessage(CodeSuccess, message, data, pager)
 }
 
 func BuildCommonErrorMessage(message string) map[string]interface{} {
-	return buildMessage(false, message, nil, nil)
+	return buildMessage(CodeFail, message, nil, nil)
+}
+
+func BuildCodeErrorMessage(code int, message string) map[string]interface{} {
+	return buildMessage(code, message, nil, nil)
 }
 
 func BuildDbErrorMessage(message string) map[string]interface{} {
-	return buildMessage(false, message, nil, nil)
+	return buildMessage(CodeDbError, message, nil, nil)
 }
 
-func BuildParamsErrorMessage() map[string]interface{} {
-	return buildMessage(false, "参数格式异常", nil, nil)
+// errs为各字段的错误信息，可以为空
+func BuildParamsErrorMessage(errs ...*FieldError) map[string]interface{} {
+	return NewParamsErrorResponse(errs...).ToMap()
 }
 
 func BuildObjectNotFountMessage() map[string]interface{} {
-	return buildMessage(false, "对象不存在", nil, nil)
+	return buildMessage(CodeNotFound, codeMessage(CodeNotFound, ""), nil, nil)
 }
@@ -0,0 +1,91 @@
+package commonlib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeJSONMap(t *testing.T, v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]interface{})
+	if err = json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// Build*Message序列化后应该与之前版本的map一致
+func TestBuildMessageJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		legacy   map[string]interface{}
+		expected string
+	}{
+		{"空提示", BuildSuccessMessage("", []string{}), `{"code":0,"message":"","content":[]}`},
+		{"nil切片", BuildSuccessMessage("", []string(nil)), `{"code":0,"message":"","content":null}`},
+		{"0", BuildSuccessMessage("ok", 0), `{"code":0,"message":"ok","content":0}`},
+		{"false", BuildSuccessMessage("", false), `{"code":0,"message":"","content":false}`},
+		{"nil", BuildSuccessMessage("", nil), `{"code":0,"message":""}`},
+		{"分页", BuildSuccessPageMessage("", []int{}, &Pager{PageId: 1, RecPerPage: 10}), `{"code":0,"message":"","content":[],"pager":{"pageId":1,"recPerPage":10,"total":0,"totalPage":0,"hasPrev":false,"hasNext":false,"offset":0}}`},
+		{"失败", BuildCommonErrorMessage("失败"), `{"code":-1,"message":"失败"}`},
+		{"失败空提示", BuildCommonErrorMessage(""), `{"code":-1,"message":""}`},
+		{"对象不存在", BuildObjectNotFountMessage(), `{"code":3001,"message":"对象不存在"}`},
+		{"参数错误", BuildParamsErrorMessage(), `{"code":1001,"message":"参数格式异常"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := decodeJSONMap(t, c.legacy)
+			expected := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(c.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("json = %v, expected %s", got, c.expected)
+			}
+		})
+	}
+}
+
+// Response序列化后的格式与Build*Message一致
+func TestResponseJSONCompatible(t *testing.T) {
+	cases := []struct {
+		name     string
+		response interface{}
+		expected string
+	}{
+		{"空切片", NewSuccessResponse("ok", []string{}), `{"code":0,"message":"ok","content":[]}`},
+		{"nil切片", NewSuccessResponse("ok", []string(nil)), `{"code":0,"message":"ok","content":null}`},
+		{"0", NewSuccessResponse("ok", 0), `{"code":0,"message":"ok","content":0}`},
+		{"默认提示", NewSuccessResponse("", false), `{"code":0,"message":"成功","content":false}`},
+		{"分页", NewPageResponse("ok", []int{}, &Pager{PageId: 1, RecPerPage: 10}), `{"code":0,"message":"ok","content":[],"pager":{"pageId":1,"recPerPage":10,"total":0,"totalPage":0,"hasPrev":false,"hasNext":false,"offset":0}}`},
+		{"失败", NewErrorResponse("失败"), `{"code":-1,"message":"失败"}`},
+		{"错误码", NewCodeErrorResponse(CodeNotFound, ""), `{"code":3001,"message":"对象不存在"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := decodeJSONMap(t, c.response)
+			expected := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(c.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("json = %v, expected %s", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestResponseJSONContent(t *testing.T) {
+	if _, ok := decodeJSONMap(t, NewSuccessResponse("", 0))["content"]; !ok {
+		t.Error("content为0时应该输出content")
+	}
+	if _, ok := decodeJSONMap(t, NewErrorResponse("失败"))["content"]; ok {
+		t.Error("失败响应不应该输出content")
+	}
+}
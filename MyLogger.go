@@ -2,8 +2,10 @@ package commonlib
 
 import (
 	"fmt"
-	"github.com/astaxie/beego"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -14,35 +16,207 @@ func init() {
 	Log = new(MyLogger)
 }
 
+// 日志输出格式
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// 日志级别
+type LogLevel int
+
+const (
+	LevelError LogLevel = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+func (level LogLevel) String() string {
+	switch level {
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warn"
+	case LevelInfo:
+		return "info"
+	case LevelDebug:
+		return "debug"
+	}
+	return "trace"
+}
+
+// 结构化日志字段
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+func StringField(key, value string) LogField {
+	return LogField{key, value}
+}
+
+func IntField(key string, value int) LogField {
+	return LogField{key, value}
+}
+
+func Int64Field(key string, value int64) LogField {
+	return LogField{key, value}
+}
+
+func FloatField(key string, value float64) LogField {
+	return LogField{key, value}
+}
+
+func BoolField(key string, value bool) LogField {
+	return LogField{key, value}
+}
+
+func DurationField(key string, value time.Duration) LogField {
+	return LogField{key, value}
+}
+
+func TimeField(key string, value time.Time) LogField {
+	return LogField{key, value}
+}
+
+// 错误信息，key为error
+func ErrorField(err error) LogField {
+	return LogField{"error", err}
+}
+
+func AnyField(key string, value interface{}) LogField {
+	return LogField{key, value}
+}
+
+/**
+ * 日志，输出到Backend，Backend为nil时使用默认的日志后端(见SetLogger)
+ *
+ * 参数中的LogField作为字段输出，其他参数拼接为日志内容
+ *
+ * example:
+ *   Log.Error("查询课表发生错误:", err)
+ *   Log.Error("查询课表发生错误", ErrorField(err), IntField("rows", n))
+ *   log := Log.With("scheduleId", scheduleId).With("childId", childId)
+ *   log.Debug("开始排课")
+ */
 type MyLogger struct {
+	Backend Logger
+
+	fields []LogField
+}
+
+// 返回附加了字段的日志，原日志不受影响
+func (log *MyLogger) With(key string, value interface{}) *MyLogger {
+	return log.WithFields(LogField{key, value})
+}
+
+func (log *MyLogger) WithFields(fields ...LogField) *MyLogger {
+	l := *log
+	l.fields = make([]LogField, 0, len(log.fields)+len(fields))
+	l.fields = append(append(l.fields, log.fields...), fields...)
+	return &l
 }
 
 func (log *MyLogger) Error(arg0 ...interface{}) {
-	_, file, line, _ := runtime.Caller(1)
-	beego.Error("(文件:", file, ",行:", line, ")", fmt.Sprint(arg0...))
+	log.output(LevelError, 2, arg0)
 }
 
 func (log *MyLogger) Debug(arg0 ...interface{}) {
-	_, file, line, _ := runtime.Caller(1)
-	beego.Debug("(文件:", file, ",行:", line, ")", fmt.Sprint(arg0...))
+	log.output(LevelDebug, 2, arg0)
 }
 
 func (log *MyLogger) Info(arg0 ...interface{}) {
-	_, file, line, _ := runtime.Caller(1)
-	beego.Info("(文件:", file, ",行:", line, ")", fmt.Sprint(arg0...))
+	log.output(LevelInfo, 2, arg0)
 }
 
 func (log *MyLogger) Warn(arg0 ...interface{}) {
-	_, file, line, _ := runtime.Caller(1)
-	beego.Warn("(文件:", file, ",行:", line, ")", fmt.Sprint(arg0...))
+	log.output(LevelWarn, 2, arg0)
 }
 
 func (log *MyLogger) Trace(arg0 ...interface{}) {
-	_, file, line, _ := runtime.Caller(1)
-	beego.Trace("(文件:", file, ",行:", line, ")", fmt.Sprint(arg0...))
+	log.output(LevelTrace, 2, arg0)
 }
 
 func (log *MyLogger) DebugSchedule(scheduleId, childId string, arg0 ...interface{}) {
-	_, file, line, _ := runtime.Caller(1)
-	beego.Trace("[scheduleId:", scheduleId, ",childId:", childId, "]", "(文件:", file, ",行:", line, ")", fmt.Sprint(arg0...), "\n")
+	log.With("scheduleId", scheduleId).With("childId", childId).output(LevelTrace, 2, arg0)
+}
+
+/**
+ * 输出日志
+ * @param depth	调用层数，用于获取调用方的文件及行号
+ */
+func (log *MyLogger) output(level LogLevel, depth int, args []interface{}) {
+	pc, file, line, _ := runtime.Caller(depth)
+	if !logEnabled(level, pc) {
+		return
+	}
+
+	message, fields := splitLogFields(args)
+
+	// 相同位置的重复日志按采样输出，见SetLogSampler
+	allow, dropped := sampleLog(level, file, line)
+	if !allow {
+		return
+	}
+	if dropped > 0 {
+		fields = append(fields, IntField("dropped", dropped))
+	}
+
+	record := &LogRecord{
+		Time:    time.Now(),
+		Level:   level,
+		File:    file,
+		Line:    line,
+		Message: message,
+		Fields:  append(log.fields[:len(log.fields):len(log.fields)], fields...),
+	}
+
+	backend := log.Backend
+	if backend == nil {
+		backend = configuredLogger()
+	}
+	backend.Write(record)
+}
+
+// 转换为便于输出的值，error及实现了String()的值(如Duration)输出为字符串
+func logFieldValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case error:
+		return val.Error()
+	case time.Time:
+		return val
+	case fmt.Stringer:
+		return val.String()
+	}
+	return v
+}
+
+// 分离日志参数中的LogField，其余参数拼接为日志内容
+func splitLogFields(args []interface{}) (string, []LogField) {
+	var fields []LogField
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if field, ok := arg.(LogField); ok {
+			fields = append(fields, field)
+		} else {
+			values = append(values, arg)
+		}
+	}
+
+	return fmt.Sprint(values...), fields
+}
+
+// text格式的字段，格式为 key=value，值中有空格时加引号
+func formatLogFields(fields []LogField) string {
+	var builder strings.Builder
+	for _, field := range fields {
+		value := fmt.Sprint(logFieldValue(field.Value))
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		builder.WriteString(" " + field.Key + "=" + value)
+	}
+	return builder.String()
 }
@@ -0,0 +1,120 @@
+package commonlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 解析json格式的日志，每行一条
+func parseJSONLogs(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("日志不是合法的json: %s, %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogJSONFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newJSONTestLogger(buf)
+
+	at := time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	logger.With("scheduleId", "s1").Error("查询课表", "发生错误:", ErrorField(errors.New("timeout")),
+		IntField("rows", 3), Int64Field("childId", 1<<40), FloatField("rate", 0.5), BoolField("retry", true),
+		DurationField("cost", 1500*time.Millisecond), TimeField("at", at), StringField("sql", "select \"a\"\n"),
+		AnyField("ids", []int{1, 2}))
+
+	records := parseJSONLogs(t, buf)
+	if len(records) != 1 {
+		t.Fatalf("日志条数 = %d", len(records))
+	}
+	record := records[0]
+
+	expected := map[string]interface{}{
+		"level":      "error",
+		"msg":        "查询课表发生错误:",
+		"scheduleId": "s1",
+		"error":      "timeout",
+		"rows":       float64(3),
+		"childId":    float64(1 << 40),
+		"rate":       0.5,
+		"retry":      true,
+		"cost":       "1.5s",
+		"at":         "2024-05-01T08:00:00+08:00",
+		"sql":        "select \"a\"\n",
+		"ids":        []interface{}{float64(1), float64(2)},
+	}
+	for key, val := range expected {
+		if actual, _ := json.Marshal(record[key]); string(actual) != mustJSON(val) {
+			t.Errorf("%s = %s, expected %s", key, actual, mustJSON(val))
+		}
+	}
+
+	if file, _ := record["file"].(string); !strings.HasSuffix(file, "MyLogger_test.go") {
+		t.Errorf("file = %v", record["file"])
+	}
+	if _, err := time.Parse(time.RFC3339, record["time"].(string)); err != nil {
+		t.Errorf("time = %v", record["time"])
+	}
+}
+
+func TestLogWithFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newJSONTestLogger(buf)
+
+	child := logger.With("requestId", "r1")
+	child.WithFields(StringField("userId", "u1")).Info("a")
+	child.Info("b")
+	logger.Info("c")
+	logger.DebugSchedule("s1", "c1", "d")
+
+	records := parseJSONLogs(t, buf)
+	cases := []struct {
+		msg      string
+		level    string
+		fields   []string
+		excluded []string
+	}{
+		{"a", "info", []string{"requestId", "userId"}, nil},
+		{"b", "info", []string{"requestId"}, []string{"userId"}},
+		{"c", "info", nil, []string{"requestId", "userId"}},
+		{"d", "trace", []string{"scheduleId", "childId"}, []string{"requestId"}},
+	}
+	if len(records) != len(cases) {
+		t.Fatalf("日志条数 = %d", len(records))
+	}
+
+	for i, c := range cases {
+		record := records[i]
+		if record["msg"] != c.msg || record["level"] != c.level {
+			t.Errorf("日志%d = %v", i, record)
+		}
+		for _, key := range c.fields {
+			if _, ok := record[key]; !ok {
+				t.Errorf("日志%s缺少字段%s", c.msg, key)
+			}
+		}
+		for _, key := range c.excluded {
+			if _, ok := record[key]; ok {
+				t.Errorf("日志%s不应该包含字段%s", c.msg, key)
+			}
+		}
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func newJSONTestLogger(buf *bytes.Buffer) *MyLogger {
+	return &MyLogger{Backend: NewWriterLogger(buf, LogFormatJSON)}
+}
@@ -0,0 +1,141 @@
+package commonlib
+
+import (
+	"net/url"
+	"strconv"
+)
+
+var (
+	// 请求中没有每页条数时使用的默认值
+	DefaultRecPerPage = 10
+
+	// 请求中每页条数的最大值
+	MaxRecPerPage = 100
+)
+
+// 分页信息，json格式为 {"pageId":1,"recPerPage":10,"total":0,"totalPage":0,...}
+type Pager struct {
+	PageId     int         `json:"pageId"`
+	RecPerPage int         `json:"recPerPage"`
+	Total      int         `json:"total"`
+	TotalPage  int         `json:"totalPage"`
+	HasPrev    bool        `json:"hasPrev"`
+	HasNext    bool        `json:"hasNext"`
+	Offset     int         `json:"offset"` // 当前页第一条记录的偏移量，用于sql的limit
+	Links      *PagerLinks `json:"links,omitempty"`
+}
+
+// 分页链接，没有对应页时为空
+type PagerLinks struct {
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+/**
+ * 创建分页信息
+ * @param pageId		第几页，小于1时为1，大于总页数时为最后一页
+ * @param recPerPage	每页几条，小于1时使用DefaultRecPerPage
+ * @param total			总条数
+ */
+func NewPager(pageId, recPerPage, total int) *Pager {
+	if recPerPage < 1 {
+		recPerPage = DefaultRecPerPage
+	}
+	if total < 0 {
+		total = 0
+	}
+
+	totalPage := (total + recPerPage - 1) / recPerPage
+
+	if pageId > totalPage {
+		pageId = totalPage
+	}
+	// 没有数据时为第1页
+	if pageId < 1 {
+		pageId = 1
+	}
+
+	return &Pager{
+		PageId:     pageId,
+		RecPerPage: recPerPage,
+		Total:      total,
+		TotalPage:  totalPage,
+		HasPrev:    pageId > 1,
+		HasNext:    pageId < totalPage,
+		Offset:     (pageId - 1) * recPerPage,
+	}
+}
+
+/**
+ * 根据baseUrl生成首页、上一页、下一页、末页链接
+ * @param baseUrl	列表地址，可以带有其他查询参数，pageId/recPerPage参数会被替换
+ *
+ * return 分页信息本身，用于链式调用
+ *
+ * example:
+ *   pager := NewPager(pageId, recPerPage, total).WithLinks("/api/schedules?childId=1")
+ */
+func (p *Pager) WithLinks(baseUrl string) *Pager {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		Log.Warn("分页链接地址格式错误:", baseUrl, ",", err)
+		return p
+	}
+
+	link := func(pageId int) string {
+		query := u.Query()
+		query.Set("pageId", strconv.Itoa(pageId))
+		query.Set("recPerPage", strconv.Itoa(p.RecPerPage))
+		pageUrl := *u
+		pageUrl.RawQuery = query.Encode()
+		return pageUrl.String()
+	}
+
+	links := &PagerLinks{First: link(1)}
+	if p.HasPrev {
+		links.Prev = link(p.PageId - 1)
+	}
+	if p.HasNext {
+		links.Next = link(p.PageId + 1)
+	}
+	if p.TotalPage > 0 {
+		links.Last = link(p.TotalPage)
+	}
+	p.Links = links
+
+	return p
+}
+
+/**
+ * 从请求参数中解析pageId、recPerPage
+ * @param query				请求参数
+ * @param maxRecPerPage		每页条数最大值，超出时使用最大值，小于1时使用MaxRecPerPage
+ *
+ * return 第几页(默认1)，每页几条(默认DefaultRecPerPage)
+ *
+ * example:
+ *   pageId, recPerPage := ParsePageParams(ctx.Request.URL.Query(), 0)
+ *   list, pager, err := DbPage(db, countSql, dataSql, countParams, params, pageId, recPerPage)
+ */
+func ParsePageParams(query url.Values, maxRecPerPage int) (int, int) {
+	if maxRecPerPage < 1 {
+		maxRecPerPage = MaxRecPerPage
+	}
+
+	pageId, err := strconv.Atoi(query.Get("pageId"))
+	if err != nil || pageId < 1 {
+		pageId = 1
+	}
+
+	recPerPage, err := strconv.Atoi(query.Get("recPerPage"))
+	if err != nil || recPerPage < 1 {
+		recPerPage = DefaultRecPerPage
+	}
+	if recPerPage > maxRecPerPage {
+		recPerPage = maxRecPerPage
+	}
+
+	return pageId, recPerPage
+}
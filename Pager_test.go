@@ -0,0 +1,106 @@
+package commonlib
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNewPager(t *testing.T) {
+	cases := []struct {
+		name       string
+		pageId     int
+		recPerPage int
+		total      int
+		expected   Pager
+	}{
+		{"没有数据", 1, 10, 0, Pager{PageId: 1, RecPerPage: 10}},
+		{"没有数据时请求第3页", 3, 10, 0, Pager{PageId: 1, RecPerPage: 10}},
+		{"第一页", 1, 10, 25, Pager{PageId: 1, RecPerPage: 10, Total: 25, TotalPage: 3, HasNext: true}},
+		{"中间页", 2, 10, 25, Pager{PageId: 2, RecPerPage: 10, Total: 25, TotalPage: 3, HasPrev: true, HasNext: true, Offset: 10}},
+		{"最后一页", 3, 10, 25, Pager{PageId: 3, RecPerPage: 10, Total: 25, TotalPage: 3, HasPrev: true, Offset: 20}},
+		{"超过最后一页", 9, 10, 25, Pager{PageId: 3, RecPerPage: 10, Total: 25, TotalPage: 3, HasPrev: true, Offset: 20}},
+		{"刚好整页", 2, 10, 20, Pager{PageId: 2, RecPerPage: 10, Total: 20, TotalPage: 2, HasPrev: true, Offset: 10}},
+		{"页码小于1", 0, 10, 25, Pager{PageId: 1, RecPerPage: 10, Total: 25, TotalPage: 3, HasNext: true}},
+		{"默认每页条数", 1, 0, 5, Pager{PageId: 1, RecPerPage: DefaultRecPerPage, Total: 5, TotalPage: 1}},
+		{"总数小于0", 1, 10, -1, Pager{PageId: 1, RecPerPage: 10}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if pager := NewPager(c.pageId, c.recPerPage, c.total); *pager != c.expected {
+				t.Errorf("NewPager() = %+v, expected %+v", *pager, c.expected)
+			}
+		})
+	}
+}
+
+func TestPagerWithLinks(t *testing.T) {
+	cases := []struct {
+		name     string
+		pager    *Pager
+		baseUrl  string
+		expected PagerLinks
+	}{
+		{
+			"保留其他参数", NewPager(2, 10, 25), "/api/schedules?childId=1&sort=time",
+			PagerLinks{
+				First: "/api/schedules?childId=1&pageId=1&recPerPage=10&sort=time",
+				Prev:  "/api/schedules?childId=1&pageId=1&recPerPage=10&sort=time",
+				Next:  "/api/schedules?childId=1&pageId=3&recPerPage=10&sort=time",
+				Last:  "/api/schedules?childId=1&pageId=3&recPerPage=10&sort=time",
+			},
+		},
+		{
+			"替换已有的分页参数", NewPager(1, 20, 50), "http://example.com/list?pageId=5&recPerPage=10",
+			PagerLinks{
+				First: "http://example.com/list?pageId=1&recPerPage=20",
+				Next:  "http://example.com/list?pageId=2&recPerPage=20",
+				Last:  "http://example.com/list?pageId=3&recPerPage=20",
+			},
+		},
+		{
+			"没有数据", NewPager(1, 10, 0), "/list",
+			PagerLinks{First: "/list?pageId=1&recPerPage=10"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			links := c.pager.WithLinks(c.baseUrl).Links
+			if links == nil || *links != c.expected {
+				t.Errorf("WithLinks() = %+v, expected %+v", links, c.expected)
+			}
+		})
+	}
+
+	// 地址格式错误时不生成链接
+	if pager := NewPager(1, 10, 5).WithLinks("%zz"); pager.Links != nil {
+		t.Errorf("WithLinks() = %+v", pager.Links)
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	cases := []struct {
+		name          string
+		query         string
+		maxRecPerPage int
+		pageId        int
+		recPerPage    int
+	}{
+		{"默认值", "", 0, 1, DefaultRecPerPage},
+		{"指定", "pageId=3&recPerPage=20", 0, 3, 20},
+		{"格式错误", "pageId=a&recPerPage=-1", 0, 1, DefaultRecPerPage},
+		{"超过最大值", "recPerPage=1000", 0, 1, MaxRecPerPage},
+		{"指定最大值", "recPerPage=50", 30, 1, 30},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(c.query)
+			pageId, recPerPage := ParsePageParams(query, c.maxRecPerPage)
+			if pageId != c.pageId || recPerPage != c.recPerPage {
+				t.Errorf("ParsePageParams() = %d, %d, expected %d, %d", pageId, recPerPage, c.pageId, c.recPerPage)
+			}
+		})
+	}
+}
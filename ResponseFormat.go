@@ -0,0 +1,425 @@
+package commonlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 响应序列化方法
+type ResponseMarshaler func(v interface{}) ([]byte, error)
+
+type responseFormat struct {
+	contentType string
+	marshal     ResponseMarshaler
+}
+
+var (
+	responseFormatLock sync.RWMutex
+	responseFormats    = map[string]*responseFormat{}
+)
+
+// 内置的响应格式
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeXML      = "application/xml"
+	ContentTypeMsgpack  = "application/msgpack"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+func init() {
+	RegisterResponseFormat(ContentTypeJSON, json.Marshal, "text/json")
+	RegisterResponseFormat(ContentTypeXML, MarshalResponseXML, "text/xml")
+	RegisterResponseFormat(ContentTypeMsgpack, MarshalResponseMsgpack, "application/x-msgpack")
+	RegisterResponseFormat(ContentTypeProtobuf, MarshalResponseProtobuf, "application/protobuf")
+}
+
+/**
+ * 注册响应格式，已注册的格式会被替换
+ * @param contentType	响应的Content-Type
+ * @param marshal		序列化方法
+ * @param aliases		请求头Accept中可以使用的其他类型
+ */
+func RegisterResponseFormat(contentType string, marshal ResponseMarshaler, aliases ...string) {
+	format := &responseFormat{contentType: contentType, marshal: marshal}
+
+	responseFormatLock.Lock()
+	defer responseFormatLock.Unlock()
+
+	for _, mediaType := range append([]string{contentType}, aliases...) {
+		responseFormats[strings.ToLower(mediaType)] = format
+	}
+}
+
+// 根据请求头Accept选择响应格式，返回响应的Content-Type，默认使用json
+// 只有其他格式被明确列出，并且json不被接受(没有列出json、*/*、application/*)或json的q值更低时才使用其他格式，
+// 因此浏览器及默认请求头(text/html,application/xml;q=0.9,*/*;q=0.8)仍然返回json
+func NegotiateFormat(accept string) string {
+	return negotiateFormat(accept).contentType
+}
+
+func negotiateFormat(accept string) *responseFormat {
+	responseFormatLock.RLock()
+	defer responseFormatLock.RUnlock()
+
+	jsonFormat := responseFormats[ContentTypeJSON]
+
+	// jsonQ: 明确列出json时的q值，jsonAccepted: json(含通配符)是否可以接受
+	var best *responseFormat
+	bestQ, jsonQ, jsonAccepted := 0.0, -1.0, false
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if v, err := strconv.ParseFloat(field[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		format, ok := responseFormats[mediaType]
+		switch {
+		case ok && format == jsonFormat:
+			jsonQ = math.Max(jsonQ, q)
+			jsonAccepted = jsonAccepted || q > 0
+		case ok:
+			// q值相同时使用先列出的格式
+			if q > bestQ {
+				best, bestQ = format, q
+			}
+		case mediaType == "*/*" || mediaType == "application/*":
+			jsonAccepted = jsonAccepted || q > 0
+		}
+	}
+
+	if best == nil {
+		return jsonFormat
+	}
+	if !jsonAccepted || (jsonQ >= 0 && bestQ > jsonQ) {
+		return best
+	}
+
+	return jsonFormat
+}
+
+/**
+ * 按请求头Accept序列化响应
+ * @param res		*Response或Build*Message返回的map
+ * @param accept	请求头Accept
+ *
+ * return Content-Type，响应内容，错误信息
+ */
+func MarshalResponse(res interface{}, accept string) (string, []byte, error) {
+	format := negotiateFormat(accept)
+
+	body, err := format.marshal(res)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return format.contentType, body, nil
+}
+
+// 转换为json对应的通用结构，保证各格式的字段与json一致
+func responseTree(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/**
+ * 序列化为xml，根节点为response，字段与json相同，
+ * 数组元素为item节点，不能作为节点名的key使用 <entry key="..."> 节点
+ */
+func MarshalResponseXML(v interface{}) ([]byte, error) {
+	tree, err := responseTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBufferString(xml.Header)
+	encoder := xml.NewEncoder(buf)
+	if err = encodeXMLValue(encoder, xml.StartElement{Name: xml.Name{Local: "response"}}, tree); err != nil {
+		return nil, err
+	}
+	if err = encoder.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeXMLValue(encoder *xml.Encoder, start xml.StartElement, v interface{}) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(val) {
+			child := xml.StartElement{Name: xml.Name{Local: key}}
+			if !isXMLName(key) {
+				child = xml.StartElement{
+					Name: xml.Name{Local: "entry"},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+				}
+			}
+			if err := encodeXMLValue(encoder, child, val[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := encodeXMLValue(encoder, xml.StartElement{Name: xml.Name{Local: "item"}}, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(val))); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7f
+		if i == 0 && !letter {
+			return false
+		}
+		if !letter && r != '-' && r != '.' && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// 序列化为MessagePack，字段与json相同
+func MarshalResponseMsgpack(v interface{}) ([]byte, error) {
+	tree, err := responseTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if err = encodeMsgpack(buf, tree); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeMsgpack(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if val {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			encodeMsgpackInt(buf, i)
+			return nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, f)
+	case string:
+		n := len(val)
+		switch {
+		case n < 32:
+			buf.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			buf.Write([]byte{0xd9, byte(n)})
+		case n <= math.MaxUint16:
+			buf.WriteByte(0xda)
+			binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(0xdb)
+			binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+		buf.WriteString(val)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(val), 0x90, 0xdc, 0xdd)
+		for _, item := range val {
+			if err := encodeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		writeMsgpackHeader(buf, len(val), 0x80, 0xde, 0xdf)
+		for _, key := range sortedKeys(val) {
+			encodeMsgpack(buf, key)
+			if err := encodeMsgpack(buf, val[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的MessagePack类型: %T", v)
+	}
+
+	return nil
+}
+
+func encodeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// 数组/map长度，长度小于16时使用fix格式
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix, b16, b32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(b32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+/**
+ * 序列化为protobuf，消息类型为google.protobuf.Struct，字段与json相同，
+ * 客户端使用Struct解析后按json的字段读取，数值为double类型
+ */
+func MarshalResponseProtobuf(v interface{}) ([]byte, error) {
+	tree, err := responseTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("protobuf格式的响应必须为对象")
+	}
+
+	return protoStruct(fields)
+}
+
+// google.protobuf.Struct: map<string, Value> fields = 1
+func protoStruct(fields map[string]interface{}) ([]byte, error) {
+	var buf []byte
+	for _, key := range sortedKeys(fields) {
+		value, err := protoValue(fields[key])
+		if err != nil {
+			return nil, err
+		}
+		var entry []byte
+		entry = protoBytes(entry, 1, []byte(key))
+		entry = protoBytes(entry, 2, value)
+		buf = protoBytes(buf, 1, entry)
+	}
+	return buf, nil
+}
+
+/**
+ * google.protobuf.Value:
+ *   null_value = 1, number_value = 2, string_value = 3, bool_value = 4, struct_value = 5, list_value = 6
+ */
+func protoValue(v interface{}) ([]byte, error) {
+	var buf []byte
+
+	switch val := v.(type) {
+	case nil:
+		buf = binary.AppendUvarint(append(buf, 1<<3|0), 0)
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.LittleEndian.AppendUint64(append(buf, 2<<3|1), math.Float64bits(f))
+	case string:
+		buf = protoBytes(buf, 3, []byte(val))
+	case bool:
+		b := uint64(0)
+		if val {
+			b = 1
+		}
+		buf = binary.AppendUvarint(append(buf, 4<<3|0), b)
+	case map[string]interface{}:
+		s, err := protoStruct(val)
+		if err != nil {
+			return nil, err
+		}
+		buf = protoBytes(buf, 5, s)
+	case []interface{}:
+		// google.protobuf.ListValue: repeated Value values = 1
+		var list []byte
+		for _, item := range val {
+			value, err := protoValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = protoBytes(list, 1, value)
+		}
+		buf = protoBytes(buf, 6, list)
+	default:
+		return nil, fmt.Errorf("不支持的protobuf类型: %T", v)
+	}
+
+	return buf, nil
+}
+
+// 写入length-delimited字段
+func protoBytes(buf []byte, field int, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
@@ -0,0 +1,38 @@
+package commonlib
+
+import "testing"
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		accept   string
+		expected string
+	}{
+		{"", ContentTypeJSON},
+		{"*/*", ContentTypeJSON},
+		{"application/*", ContentTypeJSON},
+		{"text/plain", ContentTypeJSON},
+		{"application/json", ContentTypeJSON},
+		// 浏览器及HttpClient的默认请求头
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", ContentTypeJSON},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8", ContentTypeJSON},
+		{"application/xml", ContentTypeXML},
+		{"text/xml", ContentTypeXML},
+		{"application/xml, */*", ContentTypeJSON},
+		{"application/json, application/xml", ContentTypeJSON},
+		{"application/xml, application/json", ContentTypeJSON},
+		{"application/xml, application/json;q=0.5", ContentTypeXML},
+		{"application/json;q=0, application/xml;q=0.1", ContentTypeXML},
+		{"application/msgpack", ContentTypeMsgpack},
+		{"application/x-protobuf, application/json;q=0.9", ContentTypeProtobuf},
+		{"application/xml;q=0.5, application/msgpack;q=0.8", ContentTypeMsgpack},
+		{"application/xml;q=0", ContentTypeJSON},
+	}
+
+	for _, c := range cases {
+		t.Run(c.accept, func(t *testing.T) {
+			if contentType := NegotiateFormat(c.accept); contentType != c.expected {
+				t.Errorf("NegotiateFormat(%q) = %s, expected %s", c.accept, contentType, c.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package commonlib
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`^[\w.%+-]+@[\w-]+(\.[\w-]+)+$`)
+	phonePattern = regexp.MustCompile(`^(\+?86)?1[3-9]\d{9}$`)
+
+	regexpCache sync.Map
+
+	// 各结构体类型的标签检查结果
+	validateTagCache sync.Map
+)
+
+// 结构体的校验标签错误，属于代码错误，不作为参数错误返回给调用方
+type ValidateTagError struct {
+	Type  string
+	Field string
+	Tag   string
+	Err   error
+}
+
+func (e *ValidateTagError) Error() string {
+	return fmt.Sprintf("校验标签错误(%s.%s %s): %v", e.Type, e.Field, e.Tag, e.Err)
+}
+
+/**
+ * 按字段标签校验结构体
+ * @param obj	结构体或结构体指针
+ *
+ * 支持的标签:
+ *   required:"true"     不能为空(零值)，数值字段为0时也视为空，允许0时使用指针类型，bool字段需要使用*bool
+ *   min:"1" max:"100"   数值的最小、最大值
+ *   length:"2,20"       字符串的字符数或数组的长度范围，"2,"只限制最小值，",20"只限制最大值
+ *   regexp:"^\d+$"      字符串格式
+ *   enum:"a,b,c"        取值范围
+ *   format:"email"      字符串格式，支持email、phone(手机号)
+ *
+ * 字段为空(数值字段为0)且不是required时不再校验，0需要校验时使用指针类型，
+ * 嵌套的结构体及结构体数组会递归校验，字段名为 parent.child、items[0].name
+ *
+ * return 校验失败时返回FieldErrors，可以直接传给BuildParamsErrorMessage，
+ *        标签本身有错误(如正则表达式格式错误)时返回*ValidateTagError
+ *
+ * example:
+ *   type ScheduleParams struct {
+ *     ChildId string `form:"childId" required:"true"`
+ *     Status  int    `form:"status"  enum:"0,1,2"`
+ *     Phone   string `form:"phone"   format:"phone"`
+ *   }
+ *   if err := Validate(&params); err != nil {
+ *     return BuildParamsErrorMessage(err.(FieldErrors)...)
+ *   }
+ */
+func Validate(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs FieldErrors
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *FieldErrors) error {
+	rt := rv.Type()
+	if err := checkValidateTags(rt); err != nil {
+		return err
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := validateStruct(fv, prefix, errs); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := paramName(field)
+		if name == "-" {
+			continue
+		}
+		name = prefix + name
+
+		if err := validateField(field, fv, name); err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+
+		if err := validateNested(fv, name, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 递归校验嵌套的结构体及结构体数组
+func validateNested(fv reflect.Value, name string, errs *FieldErrors) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() != reflect.TypeOf(time.Time{}) {
+			return validateStruct(fv, name+".", errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := validateNested(fv.Index(i), name+"["+strconv.Itoa(i)+"]", errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 检查结构体的校验标签，每个类型只检查一次
+func checkValidateTags(rt reflect.Type) error {
+	if err, ok := validateTagCache.Load(rt); ok {
+		if err == nil {
+			return nil
+		}
+		return err.(error)
+	}
+
+	var tagErr error
+	for i := 0; i < rt.NumField() && tagErr == nil; i++ {
+		field := rt.Field(i)
+		tagErr = checkFieldTags(field)
+		if tagErr != nil {
+			tagErr = &ValidateTagError{Type: rt.String(), Field: field.Name, Tag: string(field.Tag), Err: tagErr}
+		}
+	}
+
+	if tagErr != nil {
+		Log.Error(tagErr)
+		validateTagCache.Store(rt, tagErr)
+	} else {
+		validateTagCache.Store(rt, nil)
+	}
+
+	return tagErr
+}
+
+func checkFieldTags(field reflect.StructField) error {
+	tag := field.Tag
+
+	for _, key := range []string{"min", "max"} {
+		if val := tag.Get(key); val != "" {
+			if _, err := strconv.ParseFloat(val, 64); err != nil {
+				return fmt.Errorf("%s不是数值", key)
+			}
+		}
+	}
+
+	if length := tag.Get("length"); length != "" {
+		for _, bound := range strings.SplitN(length, ",", 2) {
+			if bound = strings.TrimSpace(bound); bound != "" {
+				if _, err := strconv.Atoi(bound); err != nil {
+					return fmt.Errorf("length格式错误: %s", length)
+				}
+			}
+		}
+	}
+
+	if pattern := tag.Get("regexp"); pattern != "" {
+		if _, err := compileRegexp(pattern); err != nil {
+			return err
+		}
+	}
+
+	switch format := tag.Get("format"); format {
+	case "", "email", "phone":
+	default:
+		return fmt.Errorf("不支持的format: %s", format)
+	}
+
+	return nil
+}
+
+func validateField(field reflect.StructField, fv reflect.Value, name string) *FieldError {
+	tag := field.Tag
+
+	if isEmptyValue(fv) {
+		if tag.Get("required") == "true" {
+			return NewFieldError(name, "valid.required")
+		}
+		return nil
+	}
+
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	if isNumberKind(fv.Kind()) {
+		number := numberValue(fv)
+		if min := tag.Get("min"); min != "" {
+			if v, err := strconv.ParseFloat(min, 64); err == nil && number < v {
+				return NewFieldError(name, "valid.min", min)
+			}
+		}
+		if max := tag.Get("max"); max != "" {
+			if v, err := strconv.ParseFloat(max, 64); err == nil && number > v {
+				return NewFieldError(name, "valid.max", max)
+			}
+		}
+	}
+
+	if length := tag.Get("length"); length != "" {
+		size := -1
+		switch fv.Kind() {
+		case reflect.String:
+			size = utf8.RuneCountInString(fv.String())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			size = fv.Len()
+		}
+		if size >= 0 {
+			bounds := strings.SplitN(length, ",", 2)
+			min, max := bounds[0], bounds[0]
+			if len(bounds) == 2 {
+				max = bounds[1]
+			}
+			if v, err := strconv.Atoi(strings.TrimSpace(min)); err == nil && size < v {
+				return NewFieldError(name, "valid.minLength", v)
+			}
+			if v, err := strconv.Atoi(strings.TrimSpace(max)); err == nil && size > v {
+				return NewFieldError(name, "valid.maxLength", v)
+			}
+		}
+	}
+
+	if fv.Kind() != reflect.String && !isNumberKind(fv.Kind()) && fv.Kind() != reflect.Bool {
+		return nil
+	}
+	value := valueString(fv)
+
+	if enum := tag.Get("enum"); enum != "" {
+		found := false
+		for _, item := range strings.Split(enum, ",") {
+			if strings.TrimSpace(item) == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return NewFieldError(name, "valid.enum", enum)
+		}
+	}
+
+	if pattern := tag.Get("regexp"); pattern != "" {
+		// 标签已经在checkValidateTags中检查过
+		if re, err := compileRegexp(pattern); err == nil && !re.MatchString(value) {
+			return NewFieldError(name, "valid.regexp")
+		}
+	}
+
+	switch tag.Get("format") {
+	case "email":
+		if !emailPattern.MatchString(value) {
+			return NewFieldError(name, "valid.email")
+		}
+	case "phone":
+		if !phonePattern.MatchString(value) {
+			return NewFieldError(name, "valid.phone")
+		}
+	}
+
+	return nil
+}
+
+// 编译并缓存正则表达式
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(pattern, re)
+	return re, nil
+}
+
+// false是有效的值，不视为空
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return false
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.IsZero()
+		}
+		return false
+	}
+	return v.IsZero()
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func numberValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func valueString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return strconv.FormatFloat(numberValue(v), 'f', -1, 64)
+}
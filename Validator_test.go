@@ -0,0 +1,89 @@
+package commonlib
+
+import (
+	"errors"
+	"testing"
+)
+
+type validateTestParams struct {
+	Name     string `form:"name"   required:"true" length:"2,10"`
+	Status   int    `form:"status" enum:"1,2"`
+	Count    int    `form:"count"  min:"1" max:"10"`
+	Level    *int   `form:"level"  min:"1"`
+	Agree    bool   `form:"agree"  required:"true"`
+	Phone    string `form:"phone"  format:"phone"`
+	Code     string `form:"code"   regexp:"^\\d{4}$"`
+	Optional *bool  `form:"optional" required:"true"`
+}
+
+func TestValidate(t *testing.T) {
+	zero := 0
+	yes := true
+
+	cases := []struct {
+		name   string
+		params validateTestParams
+		field  string // 期望出错的字段，为空时期望通过
+	}{
+		{"未传可选字段", validateTestParams{Name: "ab", Optional: &yes}, ""},
+		{"bool为false", validateTestParams{Name: "ab", Agree: false, Optional: &yes}, ""},
+		{"enum", validateTestParams{Name: "ab", Status: 3, Optional: &yes}, "status"},
+		{"min", validateTestParams{Name: "ab", Count: -1, Optional: &yes}, "count"},
+		{"max", validateTestParams{Name: "ab", Count: 11, Optional: &yes}, "count"},
+		{"指针为0时校验", validateTestParams{Name: "ab", Level: &zero, Optional: &yes}, "level"},
+		{"required", validateTestParams{Optional: &yes}, "name"},
+		{"length", validateTestParams{Name: "a", Optional: &yes}, "name"},
+		{"format", validateTestParams{Name: "ab", Phone: "123", Optional: &yes}, "phone"},
+		{"regexp", validateTestParams{Name: "ab", Code: "12a4", Optional: &yes}, "code"},
+		{"required的*bool", validateTestParams{Name: "ab"}, "optional"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := Validate(&c.params)
+			if c.field == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var errs FieldErrors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != c.field {
+				t.Errorf("Validate() error = %v, expected error on %s", err, c.field)
+			}
+		})
+	}
+}
+
+func TestValidateTagError(t *testing.T) {
+	cases := []struct {
+		name string
+		obj  interface{}
+	}{
+		{"regexp", &struct {
+			Code string `regexp:"(["`
+		}{Code: "1"}},
+		{"min", &struct {
+			Count int `min:"a"`
+		}{Count: 1}},
+		{"length", &struct {
+			Name string `length:"a,2"`
+		}{Name: "1"}},
+		{"format", &struct {
+			Name string `format:"unknown"`
+		}{Name: "1"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 标签错误时返回错误而不是panic，字段为空时同样检查
+			for i := 0; i < 2; i++ {
+				var tagErr *ValidateTagError
+				if err := Validate(c.obj); !errors.As(err, &tagErr) {
+					t.Fatalf("Validate() error = %v, expected *ValidateTagError", err)
+				}
+			}
+		})
+	}
+}
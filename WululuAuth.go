@@ -0,0 +1,203 @@
+package commonlib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 签名相关的参数名
+const (
+	SignParamAppKey    = "appKey"
+	SignParamTimestamp = "timestamp"
+	SignParamNonce     = "nonce"
+	SignParamSign      = "sign"
+)
+
+// 签名方式
+const (
+	SignTypeMd5        = "md5"
+	SignTypeHmacSha256 = "hmac-sha256"
+)
+
+// wululu接口鉴权方式，在请求发送前向参数或请求头中添加鉴权信息
+type WululuAuth interface {
+	Apply(params map[string]string, header http.Header) error
+}
+
+// appKey + timestamp + nonce 签名鉴权
+type SignAuth struct {
+	AppKey   string
+	Secret   string
+	SignType string // SignTypeMd5(默认) 或 SignTypeHmacSha256
+}
+
+func (a *SignAuth) Apply(params map[string]string, header http.Header) error {
+	params[SignParamAppKey] = a.AppKey
+	params[SignParamTimestamp] = strconv.FormatInt(time.Now().Unix(), 10)
+	params[SignParamNonce] = NewRequestId()
+
+	sign, err := SignParams(params, a.Secret, a.SignType)
+	if err != nil {
+		return err
+	}
+	params[SignParamSign] = sign
+
+	return nil
+}
+
+/**
+ * 计算参数签名
+ * @param params	参数，sign参数和空值参数不参与签名
+ * @param secret	密钥，不能为空
+ * @param signType	签名方式，为空时使用md5
+ *
+ * return 签名(32位或64位小写16进制)， 错误信息
+ *
+ * 参数按名称排序，名称和值分别URL编码(url.QueryEscape)后拼接为 k1=v1&k2=v2，
+ * 避免值中包含&或=时与其它参数组合产生相同的签名
+ * md5:         md5(k1=v1&k2=v2&key=secret)
+ * hmac-sha256: hmac_sha256(secret, k1=v1&k2=v2)
+ */
+func SignParams(params map[string]string, secret, signType string) (string, error) {
+	if secret == "" {
+		return "", NewKeyError("sign.noSecret")
+	}
+
+	keys := make([]string, 0, len(params))
+	for key, val := range params {
+		if key == SignParamSign || val == "" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(params[key]))
+	}
+	str := strings.Join(pairs, "&")
+
+	if signType == SignTypeHmacSha256 {
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write([]byte(str))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	return GetMd5String(str + "&key=" + secret), nil
+}
+
+/**
+ * 校验请求签名及时间戳，用于接收签名请求的服务端
+ * @param params	请求参数
+ * @param secret	密钥
+ * @param signType	签名方式，为空时使用md5
+ * @param window	允许的时间戳偏差，小于等于0时不校验时间戳
+ *
+ * return 校验失败的原因，通过时返回nil
+ *
+ * nonce是否重复使用需要调用方另行校验
+ */
+func VerifySign(params map[string]string, secret, signType string, window time.Duration) error {
+	sign := params[SignParamSign]
+	if sign == "" {
+		return NewKeyError("sign.missing")
+	}
+
+	if window > 0 {
+		if err := CheckTimestamp(params[SignParamTimestamp], window); err != nil {
+			return err
+		}
+	}
+
+	expected, err := SignParams(params, secret, signType)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.ToLower(sign)), []byte(expected)) != 1 {
+		return NewKeyError("sign.invalid")
+	}
+
+	return nil
+}
+
+// 校验时间戳(秒)与当前时间的偏差是否在window范围内
+func CheckTimestamp(timestamp string, window time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return NewKeyError("sign.timestamp")
+	}
+
+	diff := time.Now().Sub(time.Unix(ts, 0))
+	if diff > window || diff < -window {
+		return NewKeyError("sign.expired")
+	}
+
+	return nil
+}
+
+// Bearer token鉴权，token过期前自动重新获取
+type TokenAuth struct {
+	// 获取新的token及其有效期
+	Fetch func() (token string, expiresIn time.Duration, err error)
+
+	// 在过期前多久重新获取，默认1分钟
+	RefreshBefore time.Duration
+
+	lock     sync.Mutex
+	token    string
+	expireAt time.Time
+}
+
+func NewTokenAuth(fetch func() (string, time.Duration, error)) *TokenAuth {
+	return &TokenAuth{Fetch: fetch, RefreshBefore: time.Minute}
+}
+
+func (a *TokenAuth) Apply(params map[string]string, header http.Header) error {
+	token, err := a.Token()
+	if err != nil {
+		return err
+	}
+
+	header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+// 获取当前有效的token，即将过期时重新获取
+func (a *TokenAuth) Token() (string, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.token != "" && time.Now().Add(a.RefreshBefore).Before(a.expireAt) {
+		return a.token, nil
+	}
+
+	token, expiresIn, err := a.Fetch()
+	if err != nil {
+		Log.Error("获取token发生错误:", err)
+		return "", err
+	}
+
+	a.token = token
+	a.expireAt = time.Now().Add(expiresIn)
+
+	return token, nil
+}
+
+// 使当前token失效，下次请求时重新获取
+func (a *TokenAuth) Invalidate() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.token = ""
+}
@@ -0,0 +1,114 @@
+package commonlib
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignParams(t *testing.T) {
+	cases := []struct {
+		name     string
+		params   map[string]string
+		signType string
+		expected string
+	}{
+		{
+			name:     "md5",
+			params:   map[string]string{"appKey": "app", "timestamp": "1700000000", "nonce": "abc"},
+			expected: "4f435688f4554326a005f4b44db1b8d7",
+		},
+		{
+			name:     "hmac-sha256",
+			params:   map[string]string{"appKey": "app", "timestamp": "1700000000", "nonce": "abc"},
+			signType: SignTypeHmacSha256,
+			expected: "f2435156d48e2734f0e2ca2e87f0b91137a272683e6ab652aa0de4f5800497b8",
+		},
+		{
+			name:     "sign参数和空值不参与签名",
+			params:   map[string]string{"appKey": "app", "timestamp": "1700000000", "nonce": "abc", "sign": "xxx", "empty": ""},
+			expected: "4f435688f4554326a005f4b44db1b8d7",
+		},
+		{
+			name:     "值需要URL编码",
+			params:   map[string]string{"appKey": "app", "name": "张三", "note": "a&b=c d"},
+			expected: "6528817c07700f546b948f7180fabdf1",
+		},
+		{
+			name:     "值中包含&",
+			params:   map[string]string{"a": "1&b=2"},
+			expected: "84a9dacec5996eb5f63173bed039cd43",
+		},
+		{
+			name:     "与值中包含&的参数不冲突",
+			params:   map[string]string{"a": "1", "b": "2"},
+			expected: "673a03ff151eb7bd8ae142200dda6fa3",
+		},
+		{
+			name:     "hmac-sha256值中包含&",
+			params:   map[string]string{"a": "1&b=2"},
+			signType: SignTypeHmacSha256,
+			expected: "9e850baf497444ed8bb8baca55777375f1a6c85029c947d989e19a1899aafd37",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sign, err := SignParams(c.params, "s3cret", c.signType)
+			if err != nil {
+				t.Fatalf("SignParams() error = %v", err)
+			}
+			if sign != c.expected {
+				t.Errorf("SignParams() = %s, expected %s", sign, c.expected)
+			}
+		})
+	}
+}
+
+func TestSignParamsEmptySecret(t *testing.T) {
+	if _, err := SignParams(map[string]string{"a": "1"}, "", ""); err == nil {
+		t.Error("SignParams() with empty secret expected error")
+	}
+
+	auth := &SignAuth{AppKey: "app"}
+	if err := auth.Apply(map[string]string{}, nil); err == nil {
+		t.Error("SignAuth.Apply() with empty secret expected error")
+	}
+}
+
+func TestVerifySign(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	signed := func(params map[string]string) map[string]string {
+		sign, err := SignParams(params, "s3cret", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		params[SignParamSign] = sign
+		return params
+	}
+
+	cases := []struct {
+		name   string
+		params map[string]string
+		window time.Duration
+		valid  bool
+	}{
+		{"通过", signed(map[string]string{"appKey": "app", "timestamp": now}), 5 * time.Minute, true},
+		{"缺少签名", map[string]string{"appKey": "app", "timestamp": now}, 5 * time.Minute, false},
+		{"签名错误", map[string]string{"appKey": "app", "timestamp": now, "sign": "0123"}, 5 * time.Minute, false},
+		{"已过期", signed(map[string]string{"appKey": "app", "timestamp": expired}), 5 * time.Minute, false},
+		{"时间戳格式错误", signed(map[string]string{"appKey": "app", "timestamp": "abc"}), 5 * time.Minute, false},
+		{"不校验时间戳", signed(map[string]string{"appKey": "app", "timestamp": expired}), 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := VerifySign(c.params, "s3cret", "", c.window)
+			if (err == nil) != c.valid {
+				t.Errorf("VerifySign() error = %v, valid %v", err, c.valid)
+			}
+		})
+	}
+}
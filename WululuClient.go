@@ -0,0 +1,228 @@
+package commonlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/astaxie/beego"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// wululu接口返回的业务错误(响应中code不为0)
+type WululuError = ResponseError
+
+// wululu接口返回的非2xx响应
+type WululuStatusError struct {
+	StatusCode int
+	Body       string
+
+	// 响应内容为code不为0的wululu响应时为*WululuError，可以通过errors.As获取
+	Err error
+}
+
+func (e *WululuStatusError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("wululu接口返回状态码%d: %v", e.StatusCode, e.Err)
+	}
+	body := []rune(e.Body)
+	if len(body) > 200 {
+		body = append(body[:200], []rune("...")...)
+	}
+	return fmt.Sprintf("wululu接口返回状态码%d: %s", e.StatusCode, string(body))
+}
+
+func (e *WululuStatusError) Unwrap() error {
+	return e.Err
+}
+
+// wululu接口客户端，请求地址为 Host + path
+type WululuClient struct {
+	Host string
+	Http *HttpClient
+
+	// 鉴权方式，为nil时不添加鉴权信息
+	Auth WululuAuth
+}
+
+/**
+ * 创建wululu接口客户端
+ * @param host	接口地址，如 http://inter.wululu.com ，为空时读取配置wululuInterHost
+ *
+ * 配置了wululuAppKey、wululuAppSecret时默认使用签名鉴权，签名方式读取配置wululuSignType
+ */
+func NewWululuClient(host string) *WululuClient {
+	if host == "" {
+		host = beego.AppConfig.String("wululuInterHost")
+	}
+
+	client := &WululuClient{
+		Host: strings.TrimRight(host, "/"),
+		Http: DefaultHttpClient,
+	}
+
+	if appKey := beego.AppConfig.String("wululuAppKey"); appKey != "" {
+		client.Auth = &SignAuth{
+			AppKey:   appKey,
+			Secret:   beego.AppConfig.String("wululuAppSecret"),
+			SignType: beego.AppConfig.String("wululuSignType"),
+		}
+	}
+
+	return client
+}
+
+func (c *WululuClient) Get(path string, params map[string]string) (Result, error) {
+	return c.Call("GET", path, params)
+}
+
+func (c *WululuClient) Post(path string, params map[string]string) (Result, error) {
+	return c.Call("POST", path, params)
+}
+
+func (c *WululuClient) Put(path string, params map[string]string) (Result, error) {
+	return c.Call("PUT", path, params)
+}
+
+func (c *WululuClient) Delete(path string, params map[string]string) (Result, error) {
+	return c.Call("DELETE", path, params)
+}
+
+/**
+ * 调用wululu接口
+ * @param method	请求方法
+ * @param path		接口路径，如 /schedule/list
+ * @param params	请求参数，GET请求放在url中，其他请求以表单方式放在请求体中
+ *
+ * return 完整的响应内容(code/message/content/pager)， 错误信息
+ *
+ * 响应中code不为0时返回*WululuError，http状态码不是2xx时返回*WululuStatusError
+ *
+ * example:
+ *   res, err := client.Get("/schedule/list", map[string]string{"childId": "1"})
+ *   var list []Schedule
+ *   err = res.DecodeField("content", &list)
+ */
+func (c *WululuClient) Call(method, path string, params map[string]string) (Result, error) {
+	return c.CallContext(context.Background(), method, path, params)
+}
+
+// 调用wululu接口，ctx用于超时、取消及日志字段，请求ID通过RequestIdMiddleware传递给接口
+func (c *WululuClient) CallContext(ctx context.Context, method, path string, params map[string]string) (Result, error) {
+	tag := "Wululu" + method + "："
+	requestUrl := c.Host + "/" + strings.TrimLeft(path, "/")
+
+	LogFrom(ctx).Trace(tag + requestUrl)
+
+	resp, data, err := c.send(ctx, tag, method, requestUrl, params)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// token可能已在服务端失效，重新获取后重试一次
+		if tokenAuth, ok := c.Auth.(*TokenAuth); ok {
+			tokenAuth.Invalidate()
+			resp, data, err = c.send(ctx, tag, method, requestUrl, params)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		LogFrom(ctx).Error(tag, requestUrl, "状态码:", resp.StatusCode)
+		return wululuStatusResult(resp.StatusCode, data)
+	}
+
+	return parseWululuResult(data)
+}
+
+// 非2xx响应，响应内容为wululu业务错误时一并返回响应内容
+func wululuStatusResult(status int, data []byte) (Result, error) {
+	statusErr := &WululuStatusError{StatusCode: status, Body: string(data)}
+
+	res, err := decodeWululuResult(data)
+	var wululuErr *WululuError
+	if errors.As(err, &wululuErr) {
+		statusErr.Err = wululuErr
+		return res, statusErr
+	}
+
+	return nil, statusErr
+}
+
+func (c *WululuClient) send(ctx context.Context, tag, method, requestUrl string, params map[string]string) (*http.Response, []byte, error) {
+	log := LogFrom(ctx)
+
+	// 鉴权信息会修改参数，使用副本避免影响调用方
+	signed := make(map[string]string, len(params)+4)
+	for key, val := range params {
+		signed[key] = val
+	}
+
+	// wululu接口只返回json，不使用浏览器风格的默认Accept，避免服务端按Accept返回xml
+	header := http.Header{"Accept": {ContentTypeJSON}}
+	if c.Auth != nil {
+		if err := c.Auth.Apply(signed, header); err != nil {
+			log.Error(tag, requestUrl, "发生错误:", err)
+			return nil, nil, err
+		}
+	}
+
+	values := url.Values{}
+	for key, val := range signed {
+		values.Set(key, val)
+	}
+	query := values.Encode()
+
+	var body []byte
+	if method == "GET" {
+		if query != "" {
+			requestUrl += "?" + query
+		}
+	} else {
+		body = []byte(query)
+	}
+
+	request, err := c.Http.NewRequest(method, requestUrl, body)
+	if err != nil {
+		log.Error(tag, requestUrl, "发生错误:", err)
+		return nil, nil, err
+	}
+	for key := range header {
+		request.Header.Set(key, header.Get(key))
+	}
+
+	return c.Http.doResponse(tag, request.WithContext(ctx))
+}
+
+// 解析wululu接口响应，code不为0时返回*WululuError
+func parseWululuResult(data []byte) (Result, error) {
+	res, err := decodeWululuResult(data)
+	if res == nil && err != nil {
+		Log.Error("wululu接口响应格式错误:", string(data))
+	}
+	return res, err
+}
+
+func decodeWululuResult(data []byte) (Result, error) {
+	var res Result
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&res); err != nil {
+		return nil, errors.New("wululu接口响应格式错误: " + err.Error())
+	}
+
+	var code int
+	if err := res.DecodeField("code", &code); err != nil {
+		return res, errors.New("wululu接口响应格式错误: " + err.Error())
+	}
+
+	if code != 0 {
+		message, _ := res["message"].(string)
+		return res, &WululuError{Code: code, Message: message}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,114 @@
+package commonlib_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NateZh/commonlib"
+	"github.com/NateZh/commonlib/wululutest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWululuClientCall(t *testing.T) {
+	server := wululutest.NewServer()
+	defer server.Close()
+
+	server.Handle("GET", "/list", []string{"a", "b"})
+	server.HandleError("POST", "/add", 20001, "课程时间冲突")
+	server.HandleRaw("GET", "/html", http.StatusInternalServerError, []byte("<html><body>Internal Server Error</body></html>"))
+	server.HandleError("GET", "/params", commonlib.CodeParamsError, "参数格式异常").SetStatus(http.StatusBadRequest)
+	server.HandleRaw("GET", "/empty", http.StatusBadGateway, nil)
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		content    string
+		status     int // 期望的http状态码，0表示不应该返回*WululuStatusError
+		code       int // 期望的业务错误码，0表示不应该返回*WululuError
+		hasContent bool
+	}{
+		{"成功", "GET", "/list", "[a b]", 0, 0, true},
+		{"业务错误", "POST", "/add", "", 0, 20001, true},
+		{"500错误页面", "GET", "/html", "", http.StatusInternalServerError, 0, false},
+		{"带业务错误的400", "GET", "/params", "", http.StatusBadRequest, commonlib.CodeParamsError, true},
+		{"空响应", "GET", "/empty", "", http.StatusBadGateway, 0, false},
+		{"接口不存在", "GET", "/none", "", http.StatusNotFound, commonlib.CodeNotFound, true},
+	}
+
+	client := server.Client()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := client.Call(c.method, c.path, nil)
+
+			var statusErr *commonlib.WululuStatusError
+			if errors.As(err, &statusErr) != (c.status != 0) {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if c.status != 0 && statusErr.StatusCode != c.status {
+				t.Errorf("StatusCode = %d, expected %d", statusErr.StatusCode, c.status)
+			}
+
+			var wululuErr *commonlib.WululuError
+			if errors.As(err, &wululuErr) != (c.code != 0) {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if c.code != 0 && wululuErr.Code != c.code {
+				t.Errorf("Code = %d, expected %d", wululuErr.Code, c.code)
+			}
+
+			if (res != nil) != c.hasContent {
+				t.Errorf("Call() res = %v", res)
+			}
+			if c.content != "" && fmt.Sprint(res["content"]) != c.content {
+				t.Errorf("content = %v, expected %s", res["content"], c.content)
+			}
+		})
+	}
+}
+
+func TestWululuClientUnauthorized(t *testing.T) {
+	server := wululutest.NewServer()
+	defer server.Close()
+
+	route := server.Handle("GET", "/list", []string{"a"})
+
+	// 没有使用token鉴权时不重试
+	route.SetFail(1, http.StatusUnauthorized)
+	_, err := server.Client().Get("/list", nil)
+	var statusErr *commonlib.WululuStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if route.Calls() != 1 {
+		t.Errorf("Calls() = %d", route.Calls())
+	}
+
+	// token失效时重新获取后重试一次
+	fetched := 0
+	client := server.Client()
+	client.Auth = commonlib.NewTokenAuth(func() (string, time.Duration, error) {
+		fetched++
+		return fmt.Sprint("token", fetched), time.Hour, nil
+	})
+
+	route.SetFail(1, http.StatusUnauthorized)
+	if _, err = client.Get("/list", nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched != 2 {
+		t.Errorf("token获取次数 = %d", fetched)
+	}
+	reqs := server.RequestsTo("GET", "/list")
+	if auth := reqs[len(reqs)-1].Header.Get("Authorization"); auth != "Bearer token2" {
+		t.Errorf("Authorization = %s", auth)
+	}
+
+	// 重试后仍然是401时返回状态码
+	route.SetFail(2, http.StatusUnauthorized)
+	_, err = client.Get("/list", nil)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Get() error = %v", err)
+	}
+}
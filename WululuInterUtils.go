@@ -1,241 +1,64 @@
 package commonlib
 
 import (
-	"bytes"
-	"compress/gzip"
+	"context"
+
 	_ "github.com/astaxie/beego"
-	"io"
-	"io/ioutil"
-	"net/http"
 )
 
+// 直接返回原始响应内容，需要解析响应信封或使用配置的接口地址时使用WululuClient
 func WululuPost(url string, postStr string) ([]byte, error) {
+	return WululuPostContext(context.Background(), url, postStr)
+}
+
+func WululuGet(url string, getStr string) ([]byte, error) {
+	return WululuGetContext(context.Background(), url, getStr)
+}
+
+func WululuDelete(url string, params string) ([]byte, error) {
+	return WululuDeleteContext(context.Background(), url, params)
+}
+
+func WululuPut(url string, postStr string) ([]byte, error) {
+	return WululuPutContext(context.Background(), url, postStr)
+}
+
+// 带context的WululuPost，ctx用于超时、取消及日志字段
+func WululuPostContext(ctx context.Context, url string, postStr string) ([]byte, error) {
 
 	//url = beego.AppConfig.String("wululuInterHost")+url
 
-	Log.Trace("WululuPost :" + url)
-
-	client := &http.Client{
-		CheckRedirect: nil,
-	}
-
-	postBytesReader := bytes.NewReader([]byte(postStr))
-	reqest, _ := http.NewRequest("POST", url, postBytesReader)
-
-	reqest.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
-	reqest.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	reqest.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	reqest.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
-	//	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	reqest.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
-	reqest.Header.Set("Cache-Control", "max-age=0")
-	reqest.Header.Set("Connection", "keep-alive")
-	reqest.Header.Set("Referer", url)
-
-	resp, err := client.Do(reqest)
-
-	if err != nil {
-		Log.Error("WululuPost：", url, "发生错误:", err)
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("WululuPost：", url, "发生错误:", err)
-			return nil, err
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
-	}
-
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("WululuPost：", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
-	}
-
-	return nil, nil
+	LogFrom(ctx).Trace("WululuPost :" + url)
+
+	return DefaultHttpClient.sendContext(ctx, "WululuPost：", "POST", url, []byte(postStr))
 }
 
-func WululuGet(url string, getStr string) ([]byte, error) {
+// 带context的WululuGet
+func WululuGetContext(ctx context.Context, url string, getStr string) ([]byte, error) {
 
 	//url = beego.AppConfig.String("wululuInterHost")+url
 
-	Log.Trace("WululuGet :" + url)
-
-	client := &http.Client{
-		CheckRedirect: nil,
-	}
-
-	reqest, _ := http.NewRequest("GET", url+"?"+getStr, nil)
-
-	reqest.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
-	reqest.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	reqest.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	reqest.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
-	//	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	reqest.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
-	reqest.Header.Set("Cache-Control", "max-age=0")
-	reqest.Header.Set("Connection", "keep-alive")
-	reqest.Header.Set("Referer", url)
-
-	resp, err := client.Do(reqest)
-
-	if err != nil {
-		Log.Error("WululuGet：", url, "发生错误:", err)
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("WululuGet：", url, "发生错误:", err)
-			return nil, err
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
-	}
-
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("WululuGet：", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
-	}
-
-	return nil, nil
+	LogFrom(ctx).Trace("WululuGet :" + url)
+
+	return DefaultHttpClient.sendContext(ctx, "WululuGet：", "GET", url+"?"+getStr, nil)
 }
 
-func WululuDelete(url string, params string) ([]byte, error) {
+// 带context的WululuDelete
+func WululuDeleteContext(ctx context.Context, url string, params string) ([]byte, error) {
 
 	//url = beego.AppConfig.String("wululuInterHost")+url
 
-	Log.Trace("WululuDelete :" + url)
-
-	client := &http.Client{
-		CheckRedirect: nil,
-	}
-
-	postBytesReader := bytes.NewReader([]byte(params))
-	reqest, _ := http.NewRequest("DELETE", url, postBytesReader)
-
-	reqest.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
-	reqest.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	reqest.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	reqest.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
-	//	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	reqest.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
-	reqest.Header.Set("Cache-Control", "max-age=0")
-	reqest.Header.Set("Connection", "keep-alive")
-	reqest.Header.Set("Referer", url)
-
-	resp, err := client.Do(reqest)
-
-	if err != nil {
-		Log.Error("WululuDelete：", url, "发生错误:", err)
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("WululuDelete：", url, "发生错误:", err)
-			return nil, err
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
-	}
-
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("WululuDelete：", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
-	}
-
-	return nil, nil
+	LogFrom(ctx).Trace("WululuDelete :" + url)
+
+	return DefaultHttpClient.sendContext(ctx, "WululuDelete：", "DELETE", url, []byte(params))
 }
 
-func WululuPut(url string, postStr string) ([]byte, error) {
+// 带context的WululuPut
+func WululuPutContext(ctx context.Context, url string, postStr string) ([]byte, error) {
 
 	//url = beego.AppConfig.String("wululuInterHost")+url
 
-	Log.Trace("WululuPut :" + url)
-
-	client := &http.Client{
-		CheckRedirect: nil,
-	}
-
-	postBytesReader := bytes.NewReader([]byte(postStr))
-	reqest, _ := http.NewRequest("PUT", url, postBytesReader)
-
-	reqest.Header.Set("User-Agent", " Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/31.0.1650.63 Safari/537.36")
-	reqest.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	reqest.Header.Set("Accept-Charset", "GBK,utf-8;q=0.7,*;q=0.3")
-	reqest.Header.Set("Accept-Encoding", "gzip,deflate,sdch")
-	//	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	reqest.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	reqest.Header.Set("Accept-Language", "zh-CN,zh;q=0.8")
-	reqest.Header.Set("Cache-Control", "max-age=0")
-	reqest.Header.Set("Connection", "keep-alive")
-	reqest.Header.Set("Referer", url)
-
-	resp, err := client.Do(reqest)
-
-	if err != nil {
-		Log.Error("WululuPut：", url, "发生错误:", err)
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			Log.Error("WululuPut：", url, "发生错误:", err)
-			return nil, err
-		}
-		defer reader.Close()
-	default:
-		reader = resp.Body
-	}
-
-	if reader != nil {
-		body, err := ioutil.ReadAll(reader)
-		if err != nil {
-			Log.Error("WululuPost：", url, "发生错误:", err)
-			return nil, err
-		}
-		return body, nil
-	}
-
-	return nil, nil
+	LogFrom(ctx).Trace("WululuPut :" + url)
+
+	return DefaultHttpClient.sendContext(ctx, "WululuPut：", "PUT", url, []byte(postStr))
 }
@@ -0,0 +1,163 @@
+package commonlib
+
+import (
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 默认允许的时间戳偏差
+const DefaultSignWindow = 5 * time.Minute
+
+// nonce存储，用于拒绝重复使用nonce的请求(重放攻击)
+type NonceStore interface {
+	// 记录nonce并在expire后过期，nonce已存在时返回false
+	Add(nonce string, expire time.Duration) bool
+}
+
+// 基于内存的nonce存储，只适用于单实例部署，多实例时需要使用redis等共享存储实现NonceStore
+type MemoryNonceStore struct {
+	lock      sync.Mutex
+	items     map[string]time.Time
+	lastClean time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{items: make(map[string]time.Time), lastClean: time.Now()}
+}
+
+func (s *MemoryNonceStore) Add(nonce string, expire time.Duration) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+
+	// 每分钟清理一次过期的nonce
+	if now.Sub(s.lastClean) > time.Minute {
+		for key, expireAt := range s.items {
+			if now.After(expireAt) {
+				delete(s.items, key)
+			}
+		}
+		s.lastClean = now
+	}
+
+	if expireAt, ok := s.items[nonce]; ok && now.Before(expireAt) {
+		return false
+	}
+
+	s.items[nonce] = now.Add(expire)
+
+	return true
+}
+
+// 签名请求校验
+type SignVerifier struct {
+	// 根据appKey获取密钥，返回空字符串表示appKey无效
+	Secret func(appKey string) string
+
+	// 签名方式，为空时使用md5
+	SignType string
+
+	// 允许的时间戳偏差，小于等于0时使用DefaultSignWindow
+	Window time.Duration
+
+	// nonce存储，为nil时不校验nonce是否重复
+	Nonces NonceStore
+}
+
+/**
+ * 创建签名请求校验
+ * @param secret	根据appKey获取密钥
+ *
+ * example:
+ *   verifier := NewSignVerifier(func(appKey string) string {
+ *     return beego.AppConfig.String("wululuAppSecret")
+ *   })
+ *   beego.InsertFilter("/api/*", beego.BeforeRouter, verifier.Filter())
+ */
+func NewSignVerifier(secret func(appKey string) string) *SignVerifier {
+	return &SignVerifier{
+		Secret: secret,
+		Window: DefaultSignWindow,
+		Nonces: NewMemoryNonceStore(),
+	}
+}
+
+// 校验请求参数的签名、时间戳及nonce
+func (v *SignVerifier) Verify(params map[string]string) error {
+	appKey := params[SignParamAppKey]
+	if appKey == "" {
+		return NewKeyError("sign.noAppKey")
+	}
+
+	secret := v.Secret(appKey)
+	if secret == "" {
+		return NewKeyError("sign.appKey")
+	}
+
+	// 时间戳必须校验，否则nonce过期后可以重放
+	window := v.Window
+	if window <= 0 {
+		window = DefaultSignWindow
+	}
+
+	if err := VerifySign(params, secret, v.SignType, window); err != nil {
+		return err
+	}
+
+	if v.Nonces != nil {
+		nonce := params[SignParamNonce]
+		if nonce == "" {
+			return NewKeyError("sign.noNonce")
+		}
+		// nonce只需要在时间戳有效期内保持唯一
+		if !v.Nonces.Add(appKey+":"+nonce, 2*window) {
+			return NewKeyError("sign.replay")
+		}
+	}
+
+	return nil
+}
+
+// beego过滤器，校验失败时返回401及CodeSignError
+func (v *SignVerifier) Filter() beego.FilterFunc {
+	return func(ctx *context.Context) {
+		if err := ctx.Request.ParseForm(); err != nil {
+			rejectRequest(ctx, CodeParamsError, err)
+			return
+		}
+
+		params, err := signParamsFrom(ctx.Request.Form)
+		if err != nil {
+			rejectRequest(ctx, CodeParamsError, err)
+			return
+		}
+
+		if err := v.Verify(params); err != nil {
+			rejectRequest(ctx, CodeSignError, err)
+			return
+		}
+	}
+}
+
+// 转换为签名参数，同名参数有多个值时只有一个参与签名，其它值未经校验，因此拒绝请求
+func signParamsFrom(form url.Values) (map[string]string, error) {
+	params := make(map[string]string, len(form))
+	for key, values := range form {
+		if len(values) > 1 {
+			return nil, NewKeyError("sign.duplicate", key)
+		}
+		params[key] = form.Get(key)
+	}
+
+	return params, nil
+}
+
+func rejectRequest(ctx *context.Context, code int, err error) {
+	Log.Warn("请求校验失败:", ctx.Request.Method, " ", ctx.Request.URL.String(), ",原因:", err)
+
+	ServeResponse(ctx, NewCodeErrorResponseFrom(code, err))
+}
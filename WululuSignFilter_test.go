@@ -0,0 +1,117 @@
+package commonlib
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedRequest(t *testing.T, timestamp time.Time, nonce string) map[string]string {
+	params := map[string]string{
+		SignParamAppKey:    "app",
+		SignParamTimestamp: strconv.FormatInt(timestamp.Unix(), 10),
+		SignParamNonce:     nonce,
+		"orderId":          "1001",
+	}
+	sign, err := SignParams(params, "s3cret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params[SignParamSign] = sign
+
+	return params
+}
+
+func TestSignVerifierReplay(t *testing.T) {
+	// 字面量创建时Window为0，应使用默认的时间戳偏差
+	verifier := &SignVerifier{
+		Secret: func(appKey string) string { return "s3cret" },
+		Nonces: NewMemoryNonceStore(),
+	}
+
+	params := signedRequest(t, time.Now(), "n1")
+	if err := verifier.Verify(params); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := verifier.Verify(params); err == nil {
+		t.Error("Verify() 重复的请求应该被拒绝")
+	}
+
+	if err := verifier.Verify(signedRequest(t, time.Now(), "n2")); err != nil {
+		t.Errorf("Verify() 新的nonce error = %v", err)
+	}
+}
+
+func TestSignVerifierTimestamp(t *testing.T) {
+	verifier := &SignVerifier{
+		Secret: func(appKey string) string { return "s3cret" },
+		Nonces: NewMemoryNonceStore(),
+	}
+
+	cases := []struct {
+		name      string
+		timestamp time.Time
+		valid     bool
+	}{
+		{"当前时间", time.Now(), true},
+		{"偏差内", time.Now().Add(-4 * time.Minute), true},
+		{"已过期", time.Now().Add(-10 * time.Minute), false},
+		{"未来时间", time.Now().Add(10 * time.Minute), false},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := verifier.Verify(signedRequest(t, c.timestamp, "ts"+strconv.Itoa(i)))
+			if (err == nil) != c.valid {
+				t.Errorf("Verify() error = %v, valid %v", err, c.valid)
+			}
+		})
+	}
+}
+
+func TestSignVerifierInvalid(t *testing.T) {
+	verifier := NewSignVerifier(func(appKey string) string {
+		if appKey == "app" {
+			return "s3cret"
+		}
+		return ""
+	})
+
+	tampered := signedRequest(t, time.Now(), "t1")
+	tampered["orderId"] = "1002"
+
+	unknown := signedRequest(t, time.Now(), "t2")
+	unknown[SignParamAppKey] = "other"
+
+	noNonce := signedRequest(t, time.Now(), "")
+
+	cases := []struct {
+		name   string
+		params map[string]string
+	}{
+		{"参数被修改", tampered},
+		{"appKey无效", unknown},
+		{"缺少nonce", noNonce},
+		{"缺少appKey", map[string]string{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := verifier.Verify(c.params); err == nil {
+				t.Error("Verify() expected error")
+			}
+		})
+	}
+}
+
+func TestSignParamsFromRepeated(t *testing.T) {
+	if _, err := signParamsFrom(url.Values{"orderId": {"1001", "1002"}}); err == nil {
+		t.Error("signParamsFrom() 重复的参数应该被拒绝")
+	}
+
+	params, err := signParamsFrom(url.Values{"orderId": {"1001"}, "sign": {"abc"}})
+	if err != nil || params["orderId"] != "1001" || params["sign"] != "abc" {
+		t.Errorf("signParamsFrom() = %v, %v", params, err)
+	}
+}
@@ -0,0 +1,306 @@
+// wululu接口模拟服务，只在测试中引用，避免httptest编译进业务程序
+package wululutest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/NateZh/commonlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+/**
+ * 用于测试的wululu接口模拟服务，基于httptest在进程内运行
+ *
+ * example:
+ *   server := wululutest.NewServer()
+ *   defer server.Close()
+ *   server.Handle("GET", "/schedule/list", []string{"a", "b"})
+ *   server.HandleError("POST", "/schedule/add", -1, "参数格式异常").SetDelay(time.Second)
+ *
+ *   res, err := server.Client().Get("/schedule/list", nil)
+ *   reqs := server.RequestsTo("GET", "/schedule/list")
+ */
+type Server struct {
+	*httptest.Server
+
+	// 不为nil时校验请求签名，校验失败返回401
+	Verifier *commonlib.SignVerifier
+
+	lock     sync.Mutex
+	routes   map[string]*Route
+	requests []*Request
+}
+
+// 模拟接口的响应设置，测试过程中可能有请求正在处理，通过Set*方法修改
+type Route struct {
+	lock *sync.Mutex // 所属Server的锁
+
+	status int         // 响应状态码，默认200
+	header http.Header // 额外的响应头
+	body   []byte      // 响应内容
+
+	// 不为nil时使用该方法生成响应内容，忽略body
+	handler func(req *Request) interface{}
+
+	delay      time.Duration
+	failTimes  int
+	failStatus int
+	disconnect bool
+
+	calls int
+}
+
+// 设置响应状态码
+func (r *Route) SetStatus(status int) *Route {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.status = status
+	return r
+}
+
+// 添加响应头
+func (r *Route) SetHeader(key, value string) *Route {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	header := r.header.Clone()
+	header.Add(key, value)
+	r.header = header
+	return r
+}
+
+// 设置响应前等待的时间
+func (r *Route) SetDelay(delay time.Duration) *Route {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.delay = delay
+	return r
+}
+
+/**
+ * 模拟接口故障，之后的前times次请求返回status
+ * @param status	响应状态码，为0时使用500
+ */
+func (r *Route) SetFail(times, status int) *Route {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.failTimes = r.calls + times
+	r.failStatus = status
+	return r
+}
+
+// 设置是否直接断开连接，用于模拟网络错误
+func (r *Route) SetDisconnect(disconnect bool) *Route {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.disconnect = disconnect
+	return r
+}
+
+// 收到的请求次数
+func (r *Route) Calls() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.calls
+}
+
+// 模拟服务收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Params map[string]string
+	Body   []byte
+	Time   time.Time
+}
+
+func NewServer() *Server {
+	s := &Server{routes: make(map[string]*Route)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// 创建访问模拟服务的客户端
+func (s *Server) Client() *commonlib.WululuClient {
+	client := commonlib.NewWululuClient(s.URL)
+	client.Http = commonlib.NewHttpClient()
+
+	return client
+}
+
+// 设置接口返回成功，content为响应中的content
+func (s *Server) Handle(method, path string, content interface{}) *Route {
+	return s.HandleJSON(method, path, commonlib.BuildSuccessMessage("", content))
+}
+
+// 设置接口返回业务错误
+func (s *Server) HandleError(method, path string, code int, message string) *Route {
+	return s.HandleJSON(method, path, map[string]interface{}{"code": code, "message": message})
+}
+
+// 设置接口返回指定的json内容
+func (s *Server) HandleJSON(method, path string, v interface{}) *Route {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return s.HandleRaw(method, path, http.StatusOK, body)
+}
+
+// 设置接口返回原始内容
+func (s *Server) HandleRaw(method, path string, status int, body []byte) *Route {
+	route := &Route{lock: &s.lock, status: status, header: http.Header{}, body: body}
+
+	s.lock.Lock()
+	s.routes[method+" "+path] = route
+	s.lock.Unlock()
+
+	return route
+}
+
+// 设置接口使用方法生成响应内容，返回值按json序列化
+func (s *Server) HandleFunc(method, path string, handler func(req *Request) interface{}) *Route {
+	route := s.HandleRaw(method, path, http.StatusOK, nil)
+
+	s.lock.Lock()
+	route.handler = handler
+	s.lock.Unlock()
+
+	return route
+}
+
+// 收到的所有请求
+func (s *Server) Requests() []*Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]*Request(nil), s.requests...)
+}
+
+// 收到的指定接口的请求
+func (s *Server) RequestsTo(method, path string) []*Request {
+	var result []*Request
+	for _, req := range s.Requests() {
+		if req.Method == method && req.Path == path {
+			result = append(result, req)
+		}
+	}
+
+	return result
+}
+
+// 清空接口设置和收到的请求
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.routes = make(map[string]*Route)
+	s.requests = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ParseForm()
+
+	req := &Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Params: make(map[string]string, len(r.Form)),
+		Body:   body,
+		Time:   time.Now(),
+	}
+	for key := range r.Form {
+		req.Params[key] = r.Form.Get(key)
+	}
+
+	// 在锁内复制设置，处理过程中测试代码可以修改设置
+	var route Route
+	s.lock.Lock()
+	s.requests = append(s.requests, req)
+	matched, ok := s.routes[r.Method+" "+r.URL.Path]
+	if ok {
+		matched.calls++
+		route = *matched
+	}
+	s.lock.Unlock()
+
+	if !ok {
+		writeFakeJSON(w, http.StatusNotFound, commonlib.BuildCodeErrorMessage(commonlib.CodeNotFound, "接口不存在: "+r.Method+" "+r.URL.Path))
+		return
+	}
+
+	if s.Verifier != nil {
+		if err := s.Verifier.Verify(req.Params); err != nil {
+			writeFakeJSON(w, http.StatusUnauthorized, commonlib.BuildCodeErrorMessage(commonlib.CodeSignError, err.Error()))
+			return
+		}
+	}
+
+	if route.delay > 0 {
+		select {
+		case <-time.After(route.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if route.disconnect {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+
+	if route.calls <= route.failTimes {
+		status := route.failStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		writeFakeJSON(w, status, commonlib.BuildCommonErrorMessage("模拟接口故障"))
+		return
+	}
+
+	for key, values := range route.header {
+		for _, val := range values {
+			w.Header().Add(key, val)
+		}
+	}
+
+	if route.handler != nil {
+		writeFakeJSON(w, route.status, route.handler(req))
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(route.status)
+	w.Write(route.body)
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body = []byte(err.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
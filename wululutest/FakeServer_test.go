@@ -0,0 +1,125 @@
+package wululutest
+
+import (
+	"fmt"
+	"github.com/NateZh/commonlib"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerRouting(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.Handle("GET", "/schedule/list", []string{"a", "b"})
+	server.HandleFunc("POST", "/schedule/add", func(req *Request) interface{} {
+		return commonlib.BuildSuccessMessage("", req.Params["childId"])
+	})
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		params   map[string]string
+		expected string
+		valid    bool
+	}{
+		{"GET", "GET", "/schedule/list", nil, "[a b]", true},
+		{"HandleFunc", "POST", "/schedule/add", map[string]string{"childId": "1"}, "1", true},
+		{"方法不匹配", "POST", "/schedule/list", nil, "", false},
+		{"接口不存在", "GET", "/schedule/none", nil, "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := server.Client().Call(c.method, c.path, c.params)
+			if (err == nil) != c.valid {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if !c.valid {
+				return
+			}
+
+			if content := fmt.Sprint(res["content"]); content != c.expected {
+				t.Errorf("content = %s, expected %s", content, c.expected)
+			}
+		})
+	}
+
+	if reqs := server.RequestsTo("POST", "/schedule/add"); len(reqs) != 1 || reqs[0].Params["childId"] != "1" {
+		t.Errorf("RequestsTo() = %v", reqs)
+	}
+}
+
+func TestServerFail(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	route := server.Handle("GET", "/schedule/list", nil).SetFail(2, http.StatusBadGateway)
+	client := server.Client()
+
+	for i, valid := range []bool{false, false, true} {
+		if _, err := client.Get("/schedule/list", nil); (err == nil) != valid {
+			t.Errorf("第%d次请求 error = %v", i+1, err)
+		}
+	}
+	if route.Calls() != 3 {
+		t.Errorf("Calls() = %d", route.Calls())
+	}
+}
+
+func TestServerConcurrentSettings(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	route := server.Handle("GET", "/schedule/list", nil)
+	client := server.Client()
+
+	// 请求处理过程中修改设置，-race时不应该报告数据竞争
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Get("/schedule/list", nil)
+		}()
+		route.SetDelay(time.Millisecond).SetHeader("X-Test", "1")
+	}
+	wg.Wait()
+}
+
+func TestServerVerifier(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.Verifier = commonlib.NewSignVerifier(func(appKey string) string {
+		if appKey == "app" {
+			return "secret"
+		}
+		return ""
+	})
+	server.Handle("GET", "/schedule/list", nil)
+
+	cases := []struct {
+		name  string
+		auth  commonlib.WululuAuth
+		valid bool
+	}{
+		{"签名正确", &commonlib.SignAuth{AppKey: "app", Secret: "secret"}, true},
+		{"密钥错误", &commonlib.SignAuth{AppKey: "app", Secret: "wrong"}, false},
+		{"appKey无效", &commonlib.SignAuth{AppKey: "other", Secret: "secret"}, false},
+		{"没有签名", nil, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := server.Client()
+			client.Auth = c.auth
+			if _, err := client.Get("/schedule/list", nil); (err == nil) != c.valid {
+				t.Errorf("Get() error = %v", err)
+			}
+		})
+	}
+}